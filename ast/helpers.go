@@ -229,3 +229,80 @@ func GetParamDefValFromCompParam(compParam Node) string {
 	compParamDefaValue := compXParam.Children()[0]
 	return strings.TrimSpace(string(compParamDefaValue.Raw()))
 }
+
+// GetLayoutDecl returns the layout declaration of a root or global
+// component content, or nil. Only a declaration that comes first, after
+// comments, declares the layout.
+func GetLayoutDecl(content Node) Node {
+	if content == nil {
+		return nil
+	}
+	for _, child := range content.Children() {
+		if IsRuleName(child, "comment") {
+			continue
+		}
+		if IsRuleName(child, "layout-decl") {
+			return child
+		}
+		return nil
+	}
+	return nil
+}
+
+func GetLayoutNameFromLayoutDecl(layoutDecl Node) string {
+	layoutName := FindNodeByRuleName(layoutDecl.Children(), "layout-name")
+	if layoutName == nil {
+		return ""
+	}
+	return strings.TrimSpace(string(layoutName.Raw()))
+}
+
+// Namespace returns the namespace of a qualified component name, SHOP for
+// SHOP.PRODUCT_CARD, or "" for an unqualified name.
+func Namespace(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return ""
+	}
+	return name[:i]
+}
+
+// GlobalCompNameCandidates returns the global component names that name
+// can refer to from node, in lookup order. Qualified names are absolute.
+// Inside a global component of a namespace, an unqualified name is looked
+// up in that namespace first, then in each enclosing one.
+func GlobalCompNameCandidates(node Node, name string) []string {
+	name = strings.TrimSpace(name)
+	if node == nil || strings.Contains(name, ".") {
+		return []string{name}
+	}
+
+	globalCompDef := node
+	if !IsRuleName(node, "global-comp-def") {
+		globalCompDef = FindNode(GetAncestors(node), func(anc Node) bool {
+			return IsRuleName(anc, "global-comp-def")
+		})
+	}
+
+	candidates := []string{}
+	if globalCompDef != nil {
+		globalCompName := FindNodeByRuleName(globalCompDef.Children(), "global-comp-name")
+		if globalCompName != nil {
+			for ns := Namespace(strings.TrimSpace(string(globalCompName.Raw()))); ns != ""; ns = Namespace(ns) {
+				candidates = append(candidates, ns+"."+name)
+			}
+		}
+	}
+	return append(candidates, name)
+}
+
+// ResolveGlobalCompDef returns the global component definition that name
+// refers to from node.
+func ResolveGlobalCompDef(root Node, node Node, name string) Node {
+	for _, candidate := range GlobalCompNameCandidates(node, name) {
+		if globalCompDef := FindGlobalCompDef(root, candidate); globalCompDef != nil {
+			return globalCompDef
+		}
+	}
+	return nil
+}
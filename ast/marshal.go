@@ -0,0 +1,457 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/umono-cms/compono/rule"
+)
+
+// MarshalVersion is the version of the JSON and binary forms of a tree.
+const MarshalVersion = 1
+
+// binaryMagic starts the binary form, followed by MarshalVersion.
+var binaryMagic = []byte("CAST")
+
+var errTruncated = errors.New("ast: truncated binary tree")
+
+// The raw of a node in the binary form is its span in its source, nil, or
+// the bytes that follow.
+const (
+	rawSpan byte = iota
+	rawNil
+	rawBytes
+)
+
+// A serialized tree keeps the rule name, raw, offsets and source of every
+// node. Sources are written once and referred to by index, and a raw that
+// is the span of the node in its source is not written, since the node
+// reads it back from the source. Rules are resolved with rule.Lookup.
+
+type marshaledTree struct {
+	Version int               `json:"version"`
+	Sources []marshaledSource `json:"sources"`
+	Root    *marshaledNode    `json:"root"`
+}
+
+type marshaledSource struct {
+	Name    string `json:"name,omitempty"`
+	Layer   string `json:"layer,omitempty"`
+	Content string `json:"content"`
+}
+
+type marshaledNode struct {
+	Rule     string           `json:"rule,omitempty"`
+	Source   *int             `json:"source,omitempty"`
+	Start    int              `json:"start,omitempty"`
+	End      int              `json:"end,omitempty"`
+	Span     bool             `json:"span,omitempty"`
+	Raw      *string          `json:"raw,omitempty"`
+	Children []*marshaledNode `json:"children,omitempty"`
+}
+
+// sourceTable numbers the sources of a tree in the order they are met.
+type sourceTable struct {
+	sources []*Source
+	indexes map[*Source]int
+}
+
+func (st *sourceTable) index(source *Source) int {
+	if source == nil {
+		return -1
+	}
+	if i, ok := st.indexes[source]; ok {
+		return i
+	}
+	if st.indexes == nil {
+		st.indexes = map[*Source]int{}
+	}
+	st.indexes[source] = len(st.sources)
+	st.sources = append(st.sources, source)
+	return len(st.sources) - 1
+}
+
+// span returns the bytes of source between the offsets of node, and false
+// when they are out of its content.
+func span(source *Source, start, end int) ([]byte, bool) {
+	if source == nil || start < 0 || end < start || end > len(source.content) {
+		return nil, false
+	}
+	return source.content[start:end], true
+}
+
+func isSpan(node Node) bool {
+	start, end := node.Offsets()
+	s, ok := span(node.Source(), start, end)
+	return ok && node.Raw() != nil && bytes.Equal(node.Raw(), s)
+}
+
+func ruleName(node Node) string {
+	if node.Rule() == nil {
+		return ""
+	}
+	return node.Rule().Name()
+}
+
+func lookupRule(name string) rule.Rule {
+	if name == "" {
+		return nil
+	}
+	return rule.Lookup(name)
+}
+
+// MarshalJSON writes the tree under node as JSON. Sources and raws must be
+// valid UTF-8, use MarshalBinary for others.
+func MarshalJSON(node Node) ([]byte, error) {
+	st := &sourceTable{}
+
+	var convert func(n Node) (*marshaledNode, error)
+	convert = func(n Node) (*marshaledNode, error) {
+		start, end := n.Offsets()
+		mn := &marshaledNode{
+			Rule:  ruleName(n),
+			Start: start,
+			End:   end,
+		}
+
+		if i := st.index(n.Source()); i >= 0 {
+			mn.Source = &i
+		}
+
+		if isSpan(n) {
+			mn.Span = true
+		} else if n.Raw() != nil {
+			if !utf8.Valid(n.Raw()) {
+				return nil, fmt.Errorf("ast: raw of %s node is not valid UTF-8", mn.Rule)
+			}
+			raw := string(n.Raw())
+			mn.Raw = &raw
+		}
+
+		for _, child := range n.Children() {
+			mc, err := convert(child)
+			if err != nil {
+				return nil, err
+			}
+			mn.Children = append(mn.Children, mc)
+		}
+
+		return mn, nil
+	}
+
+	root, err := convert(node)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := marshaledTree{
+		Version: MarshalVersion,
+		Sources: []marshaledSource{},
+		Root:    root,
+	}
+	for _, source := range st.sources {
+		if !utf8.Valid(source.content) {
+			return nil, fmt.Errorf("ast: source %q is not valid UTF-8", source.name)
+		}
+		tree.Sources = append(tree.Sources, marshaledSource{
+			Name:    source.name,
+			Layer:   source.layer,
+			Content: string(source.content),
+		})
+	}
+
+	return json.Marshal(tree)
+}
+
+// UnmarshalJSON reads a tree written by MarshalJSON.
+func UnmarshalJSON(data []byte) (Node, error) {
+	var tree marshaledTree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	if tree.Version != MarshalVersion {
+		return nil, fmt.Errorf("ast: unsupported tree version %d", tree.Version)
+	}
+	if tree.Root == nil {
+		return nil, errors.New("ast: tree has no root")
+	}
+
+	sources := make([]*Source, len(tree.Sources))
+	for i, ms := range tree.Sources {
+		sources[i] = NewSource(ms.Name, []byte(ms.Content))
+		sources[i].SetLayer(ms.Layer)
+	}
+
+	var convert func(mn *marshaledNode, parent Node) (Node, error)
+	convert = func(mn *marshaledNode, parent Node) (Node, error) {
+		if mn == nil {
+			return nil, errors.New("ast: null node")
+		}
+
+		var source *Source
+		if mn.Source != nil {
+			if *mn.Source < 0 || *mn.Source >= len(sources) {
+				return nil, fmt.Errorf("ast: source %d out of range", *mn.Source)
+			}
+			source = sources[*mn.Source]
+		}
+
+		var raw []byte
+		if mn.Raw != nil {
+			raw = []byte(*mn.Raw)
+		}
+
+		n, err := newUnmarshaledNode(mn.Rule, source, mn.Start, mn.End, raw, mn.Span, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		children := make([]Node, 0, len(mn.Children))
+		for _, mc := range mn.Children {
+			child, err := convert(mc, n)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+		if len(children) > 0 {
+			n.SetChildren(children)
+		}
+
+		return n, nil
+	}
+
+	return convert(tree.Root, nil)
+}
+
+// newUnmarshaledNode builds a node of a read tree, whose raw is the span of
+// the node in source when isSpan is set.
+func newUnmarshaledNode(ruleName string, source *Source, start, end int, raw []byte, isSpan bool, parent Node) (Node, error) {
+	n := DefaultEmptyNode()
+	n.SetRule(lookupRule(ruleName))
+	n.SetSource(source)
+	n.SetOffsets(start, end)
+	n.SetParent(parent)
+
+	if !isSpan {
+		n.SetRaw(raw)
+		return n, nil
+	}
+
+	s, ok := span(source, start, end)
+	if !ok {
+		return nil, fmt.Errorf("ast: %s node is out of its source", ruleName)
+	}
+	n.SetRaw(s)
+	return n, nil
+}
+
+// MarshalBinary writes the tree under node in a compact binary form. Rule
+// names are written once, like sources, and nodes refer to them by index.
+func MarshalBinary(node Node) ([]byte, error) {
+	st := &sourceTable{}
+	ruleIndexes := map[string]int{}
+	ruleNames := []string{}
+
+	var body []byte
+	var write func(n Node)
+	write = func(n Node) {
+		name := ruleName(n)
+		ri, ok := ruleIndexes[name]
+		if !ok {
+			ri = len(ruleNames)
+			ruleIndexes[name] = ri
+			ruleNames = append(ruleNames, name)
+		}
+		body = binary.AppendUvarint(body, uint64(ri))
+
+		// source indexes are shifted by one so that zero means none
+		body = binary.AppendUvarint(body, uint64(st.index(n.Source())+1))
+
+		start, end := n.Offsets()
+		body = binary.AppendVarint(body, int64(start))
+		body = binary.AppendVarint(body, int64(end-start))
+
+		switch {
+		case isSpan(n):
+			body = append(body, rawSpan)
+		case n.Raw() == nil:
+			body = append(body, rawNil)
+		default:
+			body = append(body, rawBytes)
+			body = appendBytes(body, n.Raw())
+		}
+
+		body = binary.AppendUvarint(body, uint64(len(n.Children())))
+		for _, child := range n.Children() {
+			write(child)
+		}
+	}
+	write(node)
+
+	out := append([]byte{}, binaryMagic...)
+	out = append(out, MarshalVersion)
+
+	out = binary.AppendUvarint(out, uint64(len(st.sources)))
+	for _, source := range st.sources {
+		out = appendBytes(out, []byte(source.name))
+		out = appendBytes(out, []byte(source.layer))
+		out = appendBytes(out, source.content)
+	}
+
+	out = binary.AppendUvarint(out, uint64(len(ruleNames)))
+	for _, name := range ruleNames {
+		out = appendBytes(out, []byte(name))
+	}
+
+	return append(out, body...), nil
+}
+
+// UnmarshalBinary reads a tree written by MarshalBinary.
+func UnmarshalBinary(data []byte) (Node, error) {
+	if !bytes.HasPrefix(data, binaryMagic) || len(data) <= len(binaryMagic) {
+		return nil, errors.New("ast: not a binary tree")
+	}
+	if version := data[len(binaryMagic)]; version != MarshalVersion {
+		return nil, fmt.Errorf("ast: unsupported tree version %d", version)
+	}
+
+	br := &binaryReader{data: data[len(binaryMagic)+1:]}
+
+	sources := make([]*Source, br.count())
+	for i := range sources {
+		name := string(br.bytes())
+		layer := string(br.bytes())
+		sources[i] = NewSource(name, br.bytes())
+		sources[i].SetLayer(layer)
+	}
+
+	ruleNames := make([]string, br.count())
+	for i := range ruleNames {
+		ruleNames[i] = string(br.bytes())
+	}
+
+	var read func(parent Node) (Node, error)
+	read = func(parent Node) (Node, error) {
+		ri := br.uvarint()
+		si := br.uvarint()
+		start := int(br.varint())
+		end := start + int(br.varint())
+		rawKind := br.flag()
+		var raw []byte
+		if rawKind == rawBytes {
+			raw = br.bytes()
+		}
+		if br.err != nil {
+			return nil, br.err
+		}
+
+		if ri >= uint64(len(ruleNames)) {
+			return nil, fmt.Errorf("ast: rule %d out of range", ri)
+		}
+		if si > uint64(len(sources)) {
+			return nil, fmt.Errorf("ast: source %d out of range", si-1)
+		}
+		var source *Source
+		if si > 0 {
+			source = sources[si-1]
+		}
+
+		n, err := newUnmarshaledNode(ruleNames[ri], source, start, end, raw, rawKind == rawSpan, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		children := make([]Node, br.count())
+		for i := range children {
+			if children[i], err = read(n); err != nil {
+				return nil, err
+			}
+		}
+		if len(children) > 0 {
+			n.SetChildren(children)
+		}
+
+		return n, br.err
+	}
+
+	root, err := read(nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(br.data) > 0 {
+		return nil, errors.New("ast: trailing data after binary tree")
+	}
+	return root, nil
+}
+
+func appendBytes(b []byte, s []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// binaryReader reads the binary form, keeping the first error so that
+// reads can be checked once.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (br *binaryReader) fail() {
+	if br.err == nil {
+		br.err = errTruncated
+	}
+	br.data = nil
+}
+
+func (br *binaryReader) uvarint() uint64 {
+	v, n := binary.Uvarint(br.data)
+	if n <= 0 {
+		br.fail()
+		return 0
+	}
+	br.data = br.data[n:]
+	return v
+}
+
+func (br *binaryReader) varint() int64 {
+	v, n := binary.Varint(br.data)
+	if n <= 0 {
+		br.fail()
+		return 0
+	}
+	br.data = br.data[n:]
+	return v
+}
+
+// count reads a length, which can not exceed the bytes left since every
+// counted item takes at least one.
+func (br *binaryReader) count() int {
+	v := br.uvarint()
+	if v > uint64(len(br.data)) {
+		br.fail()
+		return 0
+	}
+	return int(v)
+}
+
+func (br *binaryReader) flag() byte {
+	if len(br.data) == 0 {
+		br.fail()
+		return 0
+	}
+	b := br.data[0]
+	br.data = br.data[1:]
+	return b
+}
+
+func (br *binaryReader) bytes() []byte {
+	length := br.count()
+	b := br.data[:length:length]
+	br.data = br.data[length:]
+	return b
+}
@@ -12,6 +12,11 @@ type Node interface {
 	HasChildren() bool
 	Raw() []byte
 	SetRaw([]byte)
+	Source() *Source
+	SetSource(*Source)
+	Offsets() (start int, end int)
+	SetOffsets(start, end int)
+	Range() Range
 }
 
 func DefaultRootNode() Node {
@@ -29,6 +34,9 @@ type node struct {
 	parent   Node
 	children []Node
 	raw      []byte
+	source   *Source
+	start    int
+	end      int
 }
 
 func (n *node) Rule() rule.Rule {
@@ -69,3 +77,24 @@ func (n *node) Raw() []byte {
 func (n *node) SetRaw(raw []byte) {
 	n.raw = raw
 }
+
+func (n *node) Source() *Source {
+	return n.source
+}
+
+func (n *node) SetSource(source *Source) {
+	n.source = source
+}
+
+func (n *node) Offsets() (int, int) {
+	return n.start, n.end
+}
+
+func (n *node) SetOffsets(start, end int) {
+	n.start = start
+	n.end = end
+}
+
+func (n *node) Range() Range {
+	return RangeOf(n)
+}
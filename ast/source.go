@@ -0,0 +1,101 @@
+package ast
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Source is the original buffer a tree was parsed from. Name identifies the
+// buffer, e.g. the name of a global component. It is empty for pages. Layer
+// names the forked instance a global component was registered in.
+type Source struct {
+	name       string
+	layer      string
+	content    []byte
+	lineStarts []int
+}
+
+func NewSource(name string, content []byte) *Source {
+	lineStarts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &Source{
+		name:       name,
+		content:    content,
+		lineStarts: lineStarts,
+	}
+}
+
+func (s *Source) Name() string {
+	return s.name
+}
+
+func (s *Source) Layer() string {
+	return s.layer
+}
+
+func (s *Source) SetLayer(layer string) {
+	s.layer = layer
+}
+
+func (s *Source) Content() []byte {
+	return s.content
+}
+
+// Position returns the line and column of the given byte offset. Lines and
+// columns are 1-based and columns count runes, not bytes.
+func (s *Source) Position(offset int) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(s.content) {
+		offset = len(s.content)
+	}
+
+	line := sort.Search(len(s.lineStarts), func(i int) bool {
+		return s.lineStarts[i] > offset
+	}) - 1
+
+	lineStart := s.lineStarts[line]
+
+	return Position{
+		Source: s.name,
+		Offset: offset,
+		Line:   line + 1,
+		Column: utf8.RuneCount(s.content[lineStart:offset]) + 1,
+	}
+}
+
+type Position struct {
+	Source string `json:"source,omitempty"`
+	Offset int    `json:"offset"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Range is the span of a node in its source. A zero Line means the node was
+// not produced by the parser, e.g. builtin component definitions.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+func (r Range) IsZero() bool {
+	return r.Start.Line == 0
+}
+
+// RangeOf resolves the offsets of a node against its source.
+func RangeOf(node Node) Range {
+	source := node.Source()
+	if source == nil {
+		return Range{}
+	}
+	start, end := node.Offsets()
+	return Range{
+		Start: source.Position(start),
+		End:   source.Position(end),
+	}
+}
@@ -11,6 +11,7 @@ const (
 
 type Component struct {
 	Name             string
+	Description      string
 	Params           []Param
 	InlineRenderable bool
 }
@@ -19,30 +20,44 @@ type Param struct {
 	Name         string
 	Type         ParamType
 	DefaultValue any
+	Description  string
+	// URL marks string parameters that hold addresses, which the URL
+	// policy applies to.
+	URL bool
 }
 
 func BuiltinComponents() []Component {
 	return []Component{
 		{
-			Name: "LINK",
+			Name:        "LINK",
+			Description: "Creates an anchor element.",
 			Params: []Param{
 				{
 					Name:         "text",
 					Type:         StringType,
 					DefaultValue: "",
+					Description:  "Text of the link.",
 				},
 				{
 					Name:         "url",
 					Type:         StringType,
 					DefaultValue: "",
+					Description:  "Address the link points to.",
+					URL:          true,
 				},
 				{
 					Name:         "new-tab",
 					Type:         BoolType,
 					DefaultValue: false,
+					Description:  "Opens the link in a new tab.",
 				},
 			},
 			InlineRenderable: true,
 		},
+		{
+			Name:             "CONTENT",
+			Description:      "Renders the content of the page or layout that uses the current layout.",
+			InlineRenderable: false,
+		},
 	}
 }
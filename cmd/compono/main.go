@@ -0,0 +1,95 @@
+// Command compono works with compono sources.
+//
+// Usage:
+//
+//	compono fmt [-l] [-w] [-global] [files...]
+//
+// fmt formats the files, or the standard input when there are none, and
+// writes the result to the standard output. With -l it lists the files
+// whose formatting differs instead, and with -w it writes the result back
+// to them. -global formats global component sources. The exit status is 2
+// for a usage error and 1 when a file cannot be read or written.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/umono-cms/compono/format"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with args and returns its exit status: 2 for a
+// usage error, 1 when formatting fails.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 1 || args[0] != "fmt" {
+		fmt.Fprintln(stderr, "usage: compono fmt [-l] [-w] [-global] [files...]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	write := flags.Bool("w", false, "write the result to the files")
+	global := flags.Bool("global", false, "format global component sources")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if err := runFmt(flags.Args(), *list, *write, *global, stdin, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func runFmt(paths []string, list, write, global bool, stdin io.Reader, stdout io.Writer) error {
+	formatSource := format.Source
+	if global {
+		formatSource = format.Global
+	}
+
+	if len(paths) == 0 {
+		source, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(formatSource(source))
+		return err
+	}
+
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		formatted := formatSource(source)
+
+		if !list && !write {
+			if _, err := stdout.Write(formatted); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if bytes.Equal(source, formatted) {
+			continue
+		}
+		if list {
+			fmt.Fprintln(stdout, path)
+		}
+		if write {
+			if err := os.WriteFile(path, formatted, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
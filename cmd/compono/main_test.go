@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run([]string{}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"vet"}, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"fmt", "-x"}, nil, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "usage: compono fmt")
+}
+
+func TestRunFmt(t *testing.T) {
+	dir := t.TempDir()
+	formatted := filepath.Join(dir, "formatted.comp")
+	unformatted := filepath.Join(dir, "unformatted.comp")
+	require.Nil(t, os.WriteFile(formatted, []byte("{{ CARD }}\n"), 0o644))
+	require.Nil(t, os.WriteFile(unformatted, []byte("{{CARD}}"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run([]string{"fmt", formatted, unformatted}, nil, &stdout, &stderr))
+	assert.Equal(t, "{{ CARD }}\n{{ CARD }}\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"fmt", "-l", formatted, unformatted}, nil, &stdout, &stderr))
+	assert.Equal(t, unformatted+"\n", stdout.String())
+	source, _ := os.ReadFile(unformatted)
+	assert.Equal(t, "{{CARD}}", string(source))
+
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"fmt", "-w", formatted, unformatted}, nil, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+	source, _ = os.ReadFile(unformatted)
+	assert.Equal(t, "{{ CARD }}\n", string(source))
+
+	assert.Equal(t, 0, run([]string{"fmt", "-l", formatted, unformatted}, nil, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunFmtStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run([]string{"fmt", "-global"}, strings.NewReader("title = \"\"\n{{title}}"), &stdout, &stderr))
+	assert.Equal(t, "title=\"\"\n{{ title }}\n", stdout.String())
+}
+
+func TestRunFmtMissingFile(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run([]string{"fmt", filepath.Join(t.TempDir(), "missing.comp")}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "missing.comp")
+}
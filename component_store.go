@@ -0,0 +1,131 @@
+package compono
+
+import (
+	"fmt"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/store"
+)
+
+type storedGlobal struct {
+	version string
+	node    ast.Node
+}
+
+func (c *compono) Store() store.ComponentStore {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+
+	return c.store
+}
+
+// SetStore sets the store that provides global components which are used
+// but not registered. Registered components take precedence. Parsed
+// components are cached by name until the store no longer has them, so
+// the cache grows with the number of names the store serves.
+func (c *compono) SetStore(componentStore store.ComponentStore) {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+
+	c.store = componentStore
+	c.storeCache = map[string]storedGlobal{}
+}
+
+// loadStoredGlobals adds to gw the global components that root uses,
+// directly or through other globals, and that are not defined otherwise.
+func (c *compono) loadStoredGlobals(root ast.Node, gw ast.Node) error {
+	if !c.hasStore() {
+		return nil
+	}
+
+	tried := map[string]bool{}
+	for {
+		missing := []string{}
+		queued := map[string]bool{}
+		for _, ref := range compRefs(root) {
+			if ast.FindLocalCompDef(localCompDefSource(root, ref.node), ref.name) != nil {
+				continue
+			}
+
+			// candidates are tried in namespace order, and a stored global
+			// overrides a builtin, like a registered one
+			for _, candidate := range ast.GlobalCompNameCandidates(ref.node, ref.name) {
+				if ast.FindGlobalCompDef(root, candidate) != nil {
+					break
+				}
+				if tried[candidate] {
+					continue
+				}
+				if !queued[candidate] {
+					queued[candidate] = true
+					missing = append(missing, candidate)
+				}
+				break
+			}
+		}
+
+		if len(missing) == 0 {
+			return nil
+		}
+
+		for _, name := range missing {
+			tried[name] = true
+
+			node, err := c.lookupLayeredStoredGlobal(name)
+			if err != nil {
+				return err
+			}
+			if node == nil {
+				continue
+			}
+
+			node.SetParent(gw)
+			gw.SetChildren(append(gw.Children(), node))
+		}
+	}
+}
+
+// lookupStoredGlobal returns a copy of the parsed global component, parsing
+// it again only when the store reports a new version. The lock is held
+// only to read and write the cache, so that conversions do not wait on
+// each other's store lookups.
+func (c *compono) lookupStoredGlobal(name string) (ast.Node, error) {
+	c.storeMu.Lock()
+	componentStore, cache := c.store, c.storeCache
+	c.storeMu.Unlock()
+
+	// the store may have been unset since the caller checked it
+	if componentStore == nil {
+		return nil, nil
+	}
+
+	comp, ok, err := componentStore.Lookup(name)
+	if err != nil {
+		return nil, NewComponoError(ErrStore, fmt.Sprintf("cannot look up global component %q: %s", name, err.Error()))
+	}
+	if !ok {
+		c.storeMu.Lock()
+		delete(cache, name)
+		c.storeMu.Unlock()
+		return nil, nil
+	}
+
+	c.storeMu.Lock()
+	cached, ok := cache[name]
+	c.storeMu.Unlock()
+
+	if !ok || cached.version != comp.Version || comp.Version == "" {
+		cached = storedGlobal{
+			version: comp.Version,
+			node:    c.parseGlobal(name, comp.Source),
+		}
+
+		// a cache replaced by SetStore meanwhile is no longer read, so
+		// writing to the old one is harmless
+		c.storeMu.Lock()
+		cache[name] = cached
+		c.storeMu.Unlock()
+	}
+
+	return c.cloneNode(cached.node), nil
+}
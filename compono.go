@@ -3,14 +3,20 @@ package compono
 import (
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/umono-cms/compono/ast"
 	"github.com/umono-cms/compono/builtin"
 	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/links"
 	"github.com/umono-cms/compono/logger"
 	"github.com/umono-cms/compono/parser"
 	"github.com/umono-cms/compono/renderer"
 	"github.com/umono-cms/compono/rule"
+	"github.com/umono-cms/compono/store"
+	"github.com/umono-cms/compono/urlpolicy"
 	"github.com/umono-cms/compono/util"
 	"github.com/umono-cms/compono/validator"
 )
@@ -23,12 +29,17 @@ const (
 	ErrGlobalNotExist
 	ErrInvalidAST
 	ErrRender
+	ErrWrapped
+	ErrInvalidGlobal
+	ErrStore
 )
 
 type Compono interface {
-	Convert(source []byte, writer io.Writer) error
-	ConvertGlobalComponent(string, []byte, io.Writer) error
-	RegisterGlobalComponent(string, []byte) error
+	Convert(source []byte, writer io.Writer, opts ...ConvertOption) error
+	ConvertGlobalComponent(name string, source []byte, writer io.Writer, opts ...ConvertOption) error
+	RegisterGlobalComponent(string, []byte, ...RegisterOption) error
+	UpsertGlobalComponent(string, []byte, ...RegisterOption) error
+	ReplaceGlobalComponents(map[string][]byte, ...RegisterOption) error
 	UnregisterGlobalComponent(string) error
 	Parser() parser.Parser
 	SetParser(parser.Parser)
@@ -40,6 +51,26 @@ type Compono interface {
 	SetErrorWrapper(errwrap.ErrorWrapper)
 	Logger() logger.Logger
 	SetLogger(logger.Logger)
+	Strict() bool
+	SetStrict(bool)
+	Locale() string
+	SetLocale(string)
+	Warnings() bool
+	SetWarnings(bool)
+	URLPolicy() *urlpolicy.Policy
+	SetURLPolicy(*urlpolicy.Policy)
+	LinkResolver() links.Resolver
+	SetLinkResolver(links.Resolver)
+	Links(source []byte, opts ...ConvertOption) ([]*links.Link, error)
+	Lint(source []byte, opts ...ConvertOption) ([]*errwrap.Diagnostic, error)
+	Store() store.ComponentStore
+	SetStore(store.ComponentStore)
+	Dependencies(source []byte) ([]string, error)
+	Dependents(name string) ([]string, error)
+	Components(source []byte) ([]ComponentInfo, error)
+	Rename(source []byte, oldName, newName string) ([]byte, error)
+	RenameGlobalComponent(oldName, newName string) error
+	Fork(layer string) Compono
 }
 
 func New() Compono {
@@ -64,6 +95,8 @@ func New() Compono {
 		logger:         log,
 		globalWrapper:  gw,
 		builtinWrapper: bw,
+		locale:         errwrap.DefaultLocale,
+		urlPolicy:      urlpolicy.Default(),
 	}
 
 	c.fillBuiltins()
@@ -79,71 +112,173 @@ type compono struct {
 	logger         logger.Logger
 	globalWrapper  ast.Node
 	builtinWrapper ast.Node
+	strict         bool
+	locale         string
+	warnings       bool
+	urlPolicy      *urlpolicy.Policy
+	linkResolver   links.Resolver
+
+	// parent is the instance this one was forked from, and layer the name
+	// of the fork.
+	parent *compono
+	layer  string
+
+	// mu guards the registered global components. Conversions work on
+	// copies taken under the read lock, so registry changes are atomic to
+	// them.
+	mu sync.RWMutex
+
+	store      store.ComponentStore
+	storeCache map[string]storedGlobal
+	storeMu    sync.Mutex
 }
 
-func (c *compono) Convert(source []byte, writer io.Writer) error {
+func (c *compono) Convert(source []byte, writer io.Writer, opts ...ConvertOption) error {
 	if len(source) == 0 {
 		return nil
 	}
 
-	root := c.parser.Parse(source, ast.DefaultRootNode())
+	root, err := c.parseRoot(source)
+	if err != nil {
+		return err
+	}
 
-	c.globalWrapper.SetParent(root)
-	root.SetChildren(append(root.Children(), c.globalWrapper))
+	return c.wrapAndRender(root, writer, c.newConvertConfig(opts))
+}
 
-	c.builtinWrapper.SetParent(root)
-	root.SetChildren(append(root.Children(), c.builtinWrapper))
+// Lint returns the errors and warnings of source, ordered by position,
+// without rendering it.
+func (c *compono) Lint(source []byte, opts ...ConvertOption) ([]*errwrap.Diagnostic, error) {
+	if len(source) == 0 {
+		return nil, nil
+	}
 
-	err := c.validator.Validate(root)
+	cfg := c.newConvertConfig(opts)
+	root, err := c.parseRoot(source)
 	if err != nil {
-		return NewComponoError(ErrInvalidAST, err.Error())
+		return nil, err
 	}
 
-	c.errorWrapper.Wrap(root)
-
-	err = c.renderer.Render(writer, root)
+	err = c.validator.Validate(root)
 	if err != nil {
-		return NewComponoError(ErrRender, err.Error())
+		return nil, NewComponoError(ErrInvalidAST, err.Error())
 	}
-	return nil
+
+	warnings := c.errorWrapper.Lint(root, errwrap.WithLocale(cfg.locale))
+	c.errorWrapper.Wrap(root, cfg.wrapOptions()...)
+
+	diagnostics := append(errwrap.Collect(root), warnings...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Range.Start, diagnostics[j].Range.Start
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Offset < b.Offset
+	})
+
+	return diagnostics, nil
+}
+
+func (c *compono) parseRoot(source []byte) (ast.Node, error) {
+	root := c.parser.Parse(source, ast.DefaultRootNode())
+
+	gw := c.newGlobalWrapper(c.cloneGlobalComponents())
+	gw.SetParent(root)
+	root.SetChildren(append(root.Children(), gw))
+
+	c.attachBuiltins(root)
+
+	if err := c.loadStoredGlobals(root, gw); err != nil {
+		return nil, err
+	}
+
+	return root, nil
 }
 
-func (c *compono) ConvertGlobalComponent(name string, source []byte, writer io.Writer) error {
+func (c *compono) attachBuiltins(root ast.Node) {
+	bw := c.cloneNode(c.builtinWrapper)
+	bw.SetParent(root)
+	root.SetChildren(append(root.Children(), bw))
+}
+
+func (c *compono) ConvertGlobalComponent(name string, source []byte, writer io.Writer, opts ...ConvertOption) error {
 	if len(source) == 0 {
 		return nil
 	}
 
-	node := ast.DefaultEmptyNode()
-	node.SetRule(rule.NewGlobalCompDef())
+	root, err := c.callRoot(name, append([]ast.Node{c.parseGlobal(name, source)}, c.cloneGlobalComponents()...), false)
+	if err != nil {
+		return err
+	}
 
-	globalCompDef := c.parser.Parse(source, node)
+	return c.wrapAndRender(root, writer, c.newConvertConfig(opts))
+}
 
+// callRoot builds a root that calls the global component name, with
+// globalCompDefs as the global components.
+func (c *compono) callRoot(name string, globalCompDefs []ast.Node, withBuiltins bool) (ast.Node, error) {
 	root := c.parser.Parse([]byte(`{{ `+name+` }}`), ast.DefaultRootNode())
 
-	gcName := ast.DefaultEmptyNode()
-	gcName.SetRule(rule.NewGlobalCompName())
-	gcName.SetParent(root)
-	gcName.SetRaw([]byte(name))
+	gw := c.newGlobalWrapper(globalCompDefs)
+	gw.SetParent(root)
+	root.SetChildren(append(root.Children(), gw))
+
+	if withBuiltins {
+		c.attachBuiltins(root)
+	}
 
-	globalCompDef.SetChildren(append([]ast.Node{gcName}, globalCompDef.Children()...))
+	if err := c.loadStoredGlobals(root, gw); err != nil {
+		return nil, err
+	}
 
+	return root, nil
+}
+
+func (c *compono) newGlobalWrapper(globalCompDefs []ast.Node) ast.Node {
 	gw := ast.DefaultEmptyNode()
 	gw.SetRule(rule.NewGlobalCompDefWrapper())
-	gw.SetParent(root)
-	gw.SetChildren(append([]ast.Node{globalCompDef}, c.cloneGlobalComponents()...))
+	gw.SetChildren(globalCompDefs)
 
-	for _, child := range gw.Children() {
+	for _, child := range globalCompDefs {
 		child.SetParent(gw)
 	}
 
-	root.SetChildren(append(root.Children(), gw))
+	return gw
+}
 
+func (c *compono) parseGlobal(name string, source []byte) ast.Node {
+	src := ast.NewSource(name, source)
+	src.SetLayer(c.layer)
+
+	node := ast.DefaultEmptyNode()
+	node.SetRule(rule.NewGlobalCompDef())
+	node.SetSource(src)
+
+	parsed := c.parser.Parse(source, node)
+
+	globalCompName := ast.DefaultEmptyNode()
+	globalCompName.SetRule(rule.NewGlobalCompName())
+	globalCompName.SetParent(parsed)
+	globalCompName.SetRaw([]byte(name))
+
+	parsed.SetChildren(append([]ast.Node{globalCompName}, parsed.Children()...))
+
+	return parsed
+}
+
+func (c *compono) wrapAndRender(root ast.Node, writer io.Writer, cfg *convertConfig) error {
 	err := c.validator.Validate(root)
 	if err != nil {
 		return NewComponoError(ErrInvalidAST, err.Error())
 	}
 
-	c.errorWrapper.Wrap(root)
+	c.errorWrapper.Wrap(root, append(cfg.wrapOptions(), errwrap.WithWarnings(cfg.warnings))...)
+
+	if cfg.strict {
+		if diagnostics := errwrap.Collect(root); len(diagnostics) > 0 {
+			return newWrappedError(diagnostics)
+		}
+	}
 
 	err = c.renderer.Render(writer, root)
 	if err != nil {
@@ -152,42 +287,190 @@ func (c *compono) ConvertGlobalComponent(name string, source []byte, writer io.W
 	return nil
 }
 
-func (c *compono) RegisterGlobalComponent(name string, source []byte) error {
-	if !util.IsScreamingSnakeCase(name) {
-		return NewComponoError(ErrInvalidGlobalName, fmt.Sprintf("invalid global component name %q: must be SCREAMING_SNAKE_CASE (digits allowed)", name))
+func (c *compono) RegisterGlobalComponent(name string, source []byte, opts ...RegisterOption) error {
+	cfg := c.newRegisterConfig(opts)
+	name = cfg.qualify(name)
+	if err := validateGlobalName(name); err != nil {
+		return err
 	}
 
+	parsed := c.parseGlobal(name, source)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if registered := c.getGlobalCompDefByName(name); registered != nil {
 		return NewComponoError(ErrGlobalAlreadyRegistered, fmt.Sprintf("cannot register global component %q: already registered", name))
 	}
 
-	node := ast.DefaultEmptyNode()
-	node.SetRule(rule.NewGlobalCompDef())
+	if cfg.validate {
+		if err := c.validateGlobals([]string{name}, append([]ast.Node{parsed}, c.globalWrapper.Children()...)); err != nil {
+			return err
+		}
+	}
 
-	parsed := c.parser.Parse(source, node)
+	c.globalWrapper.SetChildren(append([]ast.Node{parsed}, c.globalWrapper.Children()...))
+	parsed.SetParent(c.globalWrapper)
 
-	globalCompName := ast.DefaultEmptyNode()
-	globalCompName.SetRule(rule.NewGlobalCompName())
-	globalCompName.SetParent(parsed)
-	globalCompName.SetRaw([]byte(name))
+	return nil
+}
 
-	parsed.SetChildren(append([]ast.Node{globalCompName}, parsed.Children()...))
-	c.globalWrapper.SetChildren(append([]ast.Node{parsed}, c.globalWrapper.Children()...))
+// UpsertGlobalComponent registers the global component or replaces the
+// registered one in a single step.
+func (c *compono) UpsertGlobalComponent(name string, source []byte, opts ...RegisterOption) error {
+	cfg := c.newRegisterConfig(opts)
+	name = cfg.qualify(name)
+	if err := validateGlobalName(name); err != nil {
+		return err
+	}
+
+	parsed := c.parseGlobal(name, source)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	others := ast.FilterNodes(c.globalWrapper.Children(), func(gc ast.Node) bool {
+		return globalCompName(gc) != name
+	})
+
+	if cfg.validate {
+		if err := c.validateGlobals([]string{name}, append([]ast.Node{parsed}, others...)); err != nil {
+			return err
+		}
+	}
+
+	globalComps := make([]ast.Node, 0, len(others)+1)
+	replaced := false
+	for _, gc := range c.globalWrapper.Children() {
+		if globalCompName(gc) != name {
+			globalComps = append(globalComps, gc)
+			continue
+		}
+		if !replaced {
+			globalComps = append(globalComps, parsed)
+			replaced = true
+		}
+	}
+	if !replaced {
+		globalComps = append([]ast.Node{parsed}, globalComps...)
+	}
+
+	c.globalWrapper.SetChildren(globalComps)
+	parsed.SetParent(c.globalWrapper)
 
 	return nil
 }
 
+// ReplaceGlobalComponents swaps all registered global components, or all
+// of a namespace given with WithNamespace, for the given set. Nothing
+// changes unless every component of the set is valid.
+func (c *compono) ReplaceGlobalComponents(sources map[string][]byte, opts ...RegisterOption) error {
+	cfg := c.newRegisterConfig(opts)
+
+	names := make([]string, 0, len(sources))
+	qualified := make(map[string][]byte, len(sources))
+	for name, source := range sources {
+		name = cfg.qualify(name)
+		if err := validateGlobalName(name); err != nil {
+			return err
+		}
+		names = append(names, name)
+		qualified[name] = source
+	}
+	sort.Strings(names)
+
+	globalComps := make([]ast.Node, len(names))
+	for i, name := range names {
+		globalComps[i] = c.parseGlobal(name, qualified[name])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cfg.namespace != "" {
+		// components outside the namespace are kept
+		for _, gc := range c.globalWrapper.Children() {
+			if !strings.HasPrefix(globalCompName(gc), cfg.namespace+".") {
+				globalComps = append(globalComps, gc)
+			}
+		}
+	}
+
+	if cfg.validate {
+		if err := c.validateGlobals(names, globalComps); err != nil {
+			return err
+		}
+	}
+
+	c.globalWrapper.SetChildren(globalComps)
+	for _, gc := range globalComps {
+		gc.SetParent(c.globalWrapper)
+	}
+
+	return nil
+}
+
+func validateGlobalName(name string) error {
+	if !util.IsComponentName(name) {
+		return NewComponoError(ErrInvalidGlobalName, fmt.Sprintf("invalid global component name %q: must be SCREAMING_SNAKE_CASE (digits allowed), optionally in dotted namespaces", name))
+	}
+	return nil
+}
+
+// validateGlobals reports the errors pages calling the named global
+// components would show when globalCompDefs are the registered globals,
+// including cycles between them.
+func (c *compono) validateGlobals(names []string, globalCompDefs []ast.Node) error {
+	diagnostics := []*errwrap.Diagnostic{}
+
+	for _, name := range names {
+		cloned := make([]ast.Node, len(globalCompDefs))
+		for i, gc := range globalCompDefs {
+			cloned[i] = c.cloneNode(gc)
+		}
+
+		root, err := c.callRoot(name, c.withParentGlobals(cloned), true)
+		if err != nil {
+			return err
+		}
+
+		ds, err := c.collectDiagnostics(root)
+		if err != nil {
+			return err
+		}
+		diagnostics = append(diagnostics, ds...)
+	}
+
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	compErr := newWrappedError(diagnostics)
+	compErr.Code = ErrInvalidGlobal
+	compErr.Message = fmt.Sprintf("cannot register global component(s) %s: %s", strings.Join(names, ", "), compErr.Message)
+	return compErr
+}
+
+func (c *compono) collectDiagnostics(root ast.Node) ([]*errwrap.Diagnostic, error) {
+	err := c.validator.Validate(root)
+	if err != nil {
+		return nil, NewComponoError(ErrInvalidAST, err.Error())
+	}
+
+	c.errorWrapper.Wrap(root, c.newConvertConfig(nil).wrapOptions()...)
+	return errwrap.Collect(root), nil
+}
+
 func (c *compono) UnregisterGlobalComponent(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if registered := c.getGlobalCompDefByName(name); registered == nil {
 		return NewComponoError(ErrGlobalNotExist, fmt.Sprintf("cannot unregister global component %q: does not exist", name))
 	}
 
 	globalComps := ast.FilterNodes(c.globalWrapper.Children(), func(gc ast.Node) bool {
-		globalCompName := ast.FindNodeByRuleName(gc.Children(), "global-comp-name")
-		if string(globalCompName.Raw()) == name {
-			return false
-		}
-		return true
+		return globalCompName(gc) != name
 	})
 
 	c.globalWrapper.SetChildren(globalComps)
@@ -234,6 +517,51 @@ func (c *compono) SetLogger(logger logger.Logger) {
 	c.logger = logger
 }
 
+func (c *compono) Strict() bool {
+	return c.strict
+}
+
+func (c *compono) SetStrict(strict bool) {
+	c.strict = strict
+}
+
+func (c *compono) Locale() string {
+	return c.locale
+}
+
+func (c *compono) SetLocale(locale string) {
+	c.locale = locale
+}
+
+func (c *compono) Warnings() bool {
+	return c.warnings
+}
+
+func (c *compono) SetWarnings(warnings bool) {
+	c.warnings = warnings
+}
+
+// URLPolicy returns the policy that links and URL parameters are checked
+// against, urlpolicy.Default() unless set.
+func (c *compono) URLPolicy() *urlpolicy.Policy {
+	return c.urlPolicy
+}
+
+// SetURLPolicy sets the URL policy. A nil policy allows every URL.
+func (c *compono) SetURLPolicy(policy *urlpolicy.Policy) {
+	c.urlPolicy = policy
+}
+
+func (c *compono) LinkResolver() links.Resolver {
+	return c.linkResolver
+}
+
+// SetLinkResolver sets the resolver of link URLs, which runs before the
+// URL policy. A nil resolver keeps URLs as written.
+func (c *compono) SetLinkResolver(resolver links.Resolver) {
+	c.linkResolver = resolver
+}
+
 func (c *compono) getGlobalCompDefByName(name string) ast.Node {
 	for _, gcd := range c.globalWrapper.Children() {
 		if gcd.Rule().Name() != "global-comp-def" {
@@ -248,7 +576,24 @@ func (c *compono) getGlobalCompDefByName(name string) ast.Node {
 	return nil
 }
 
+func globalCompName(globalCompDef ast.Node) string {
+	name := ast.FindNodeByRuleName(globalCompDef.Children(), "global-comp-name")
+	if name == nil {
+		return ""
+	}
+	return string(name.Raw())
+}
+
+// cloneGlobalComponents returns copies of the global components registered
+// in this layer and, unless shadowed, in the layers it was forked from.
 func (c *compono) cloneGlobalComponents() []ast.Node {
+	return c.withParentGlobals(c.cloneOwnGlobalComponents())
+}
+
+func (c *compono) cloneOwnGlobalComponents() []ast.Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	children := c.globalWrapper.Children()
 	if len(children) == 0 {
 		return nil
@@ -269,6 +614,8 @@ func (c *compono) cloneNode(node ast.Node) ast.Node {
 	clone := ast.DefaultEmptyNode()
 	clone.SetRule(node.Rule())
 	clone.SetRaw(node.Raw())
+	clone.SetSource(node.Source())
+	clone.SetOffsets(node.Offsets())
 
 	children := node.Children()
 	if len(children) > 0 {
@@ -289,12 +636,36 @@ func (c *compono) fillBuiltins() {
 }
 
 type ComponoError struct {
-	Code    ErrorCode
-	Message string
+	Code        ErrorCode
+	Message     string
+	Diagnostics []*errwrap.Diagnostic
 }
 
 func (e *ComponoError) Error() string { return e.Message }
 
+func (e *ComponoError) Unwrap() []error {
+	if len(e.Diagnostics) == 0 {
+		return nil
+	}
+	errs := make([]error, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		errs[i] = d
+	}
+	return errs
+}
+
 func NewComponoError(code ErrorCode, msg string) *ComponoError {
 	return &ComponoError{Code: code, Message: msg}
 }
+
+func newWrappedError(diagnostics []*errwrap.Diagnostic) *ComponoError {
+	lines := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		lines[i] = d.Error()
+	}
+	return &ComponoError{
+		Code:        ErrWrapped,
+		Message:     fmt.Sprintf("content has %d error(s):\n%s", len(diagnostics), strings.Join(lines, "\n")),
+		Diagnostics: diagnostics,
+	}
+}
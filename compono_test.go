@@ -2,15 +2,30 @@ package compono
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/format"
+	"github.com/umono-cms/compono/links"
 	"github.com/umono-cms/compono/logger"
+	"github.com/umono-cms/compono/renderer/html"
+	jsonrenderer "github.com/umono-cms/compono/renderer/json"
+	"github.com/umono-cms/compono/renderer/markdown"
+	"github.com/umono-cms/compono/renderer/text"
+	"github.com/umono-cms/compono/store"
+	"github.com/umono-cms/compono/urlpolicy"
 )
 
 type componoTestSuite struct {
@@ -18,6 +33,32 @@ type componoTestSuite struct {
 }
 
 func (s *componoTestSuite) TestGolden() {
+	s.runGolden("testdata/output", func(comp Compono) {})
+}
+
+func (s *componoTestSuite) TestGoldenText() {
+	s.runGolden("testdata/text_output", func(comp Compono) {
+		comp.SetRenderer(text.NewRenderer(comp.Logger()))
+	})
+}
+
+func (s *componoTestSuite) TestGoldenMarkdown() {
+	s.runGolden("testdata/markdown_output", func(comp Compono) {
+		comp.SetRenderer(markdown.NewRenderer(comp.Logger()))
+	})
+}
+
+func (s *componoTestSuite) TestGoldenJSON() {
+	s.runGolden("testdata/json_output", func(comp Compono) {
+		comp.SetRenderer(jsonrenderer.NewRenderer(comp.Logger()))
+	})
+}
+
+// runGolden converts every testdata/input file, with the globals of its
+// directory in testdata/input/global registered, and compares the output
+// to the golden file of the same name in outputDir. setup configures each
+// instance before the globals are registered.
+func (s *componoTestSuite) runGolden(outputDir string, setup func(Compono)) {
 	inputFiles, err := filepath.Glob("testdata/input/*.comp")
 	require.Nil(s.T(), err)
 	require.NotEmpty(s.T(), inputFiles, "no .comp files found")
@@ -27,34 +68,187 @@ func (s *componoTestSuite) TestGolden() {
 		input, err := os.ReadFile(inputPath)
 		require.Nil(s.T(), err)
 
-		globalFiles, err := filepath.Glob("testdata/input/global/" + strings.TrimSuffix(name, ".comp") + "/*.comp")
-		require.Nil(s.T(), err)
-
-		comp := New()
-		comp.Logger().SetLogLevel(logger.All)
-
-		for _, gPath := range globalFiles {
-			globalCompName := filepath.Base(gPath)
-			globalInput, err := os.ReadFile(gPath)
-			require.Nil(s.T(), err)
-
-			err = comp.RegisterGlobalComponent(strings.TrimSuffix(globalCompName, ".comp"), []byte(strings.TrimSpace(string(globalInput))))
-			assert.Nil(s.T(), err)
-		}
+		comp := s.newGoldenCompono(name, setup)
 
 		var buf bytes.Buffer
 		err = comp.Convert([]byte(strings.TrimSpace(string(input))), &buf)
 		assert.Nil(s.T(), err)
 
 		goldenPath := filepath.Join(
-			"testdata/output",
+			outputDir,
 			strings.TrimSuffix(name, ".comp")+".golden",
 		)
 
 		golden, err := os.ReadFile(goldenPath)
 		require.Nil(s.T(), err, "golden file missing")
 
-		assert.Equal(s.T(), strings.TrimSpace(string(golden)), buf.String(), "from %s", inputPath)
+		// leading spaces are significant in text goldens
+		assert.Equal(s.T(), strings.TrimRight(string(golden), "\n"), buf.String(), "from %s", inputPath)
+	}
+}
+
+func (s *componoTestSuite) newGoldenCompono(name string, setup func(Compono)) Compono {
+	comp := New()
+	comp.Logger().SetLogLevel(logger.All)
+	setup(comp)
+
+	for globalCompName, globalInput := range s.goldenGlobals(name) {
+		err := comp.RegisterGlobalComponent(globalCompName, globalInput)
+		assert.Nil(s.T(), err)
+	}
+
+	return comp
+}
+
+// goldenGlobals reads the globals of the testdata/input file name, keyed by
+// their qualified names.
+func (s *componoTestSuite) goldenGlobals(name string) map[string][]byte {
+	globalDir := "testdata/input/global/" + strings.TrimSuffix(name, ".comp")
+	globalFiles, err := filepath.Glob(globalDir + "/*.comp")
+	require.Nil(s.T(), err)
+	namespacedFiles, err := filepath.Glob(globalDir + "/*/*.comp")
+	require.Nil(s.T(), err)
+	globalFiles = append(globalFiles, namespacedFiles...)
+
+	globals := map[string][]byte{}
+	for _, gPath := range globalFiles {
+		globalCompName, err := filepath.Rel(globalDir, gPath)
+		require.Nil(s.T(), err)
+		globalInput, err := os.ReadFile(gPath)
+		require.Nil(s.T(), err)
+
+		globals[strings.ReplaceAll(strings.TrimSuffix(filepath.ToSlash(globalCompName), ".comp"), "/", ".")] = []byte(strings.TrimSpace(string(globalInput)))
+	}
+
+	return globals
+}
+
+func (s *componoTestSuite) TestFormat() {
+	inputFiles, err := filepath.Glob("testdata/input/*.comp")
+	require.Nil(s.T(), err)
+
+	for _, inputPath := range inputFiles {
+		name := filepath.Base(inputPath)
+		input, err := os.ReadFile(inputPath)
+		require.Nil(s.T(), err)
+		source := []byte(strings.TrimSpace(string(input)))
+
+		var want bytes.Buffer
+		assert.Nil(s.T(), s.newGoldenCompono(name, func(Compono) {}).Convert(source, &want))
+
+		comp := New()
+		for globalCompName, globalInput := range s.goldenGlobals(name) {
+			formatted := format.Global(globalInput)
+			assert.Equal(s.T(), string(formatted), string(format.Global(formatted)), "%s of %s is formatted twice", globalCompName, inputPath)
+			assert.Nil(s.T(), comp.RegisterGlobalComponent(globalCompName, formatted))
+		}
+
+		formatted := format.Source(source)
+		assert.Equal(s.T(), string(formatted), string(format.Source(formatted)), "%s is formatted twice", inputPath)
+
+		var got bytes.Buffer
+		assert.Nil(s.T(), comp.Convert(formatted, &got))
+		assert.Equal(s.T(), want.String(), got.String(), "from %s", inputPath)
+	}
+}
+
+func (s *componoTestSuite) TestGoldenWithFSStore() {
+	globalDirs, err := filepath.Glob("testdata/input/global/*")
+	require.Nil(s.T(), err)
+	require.NotEmpty(s.T(), globalDirs)
+
+	for _, globalDir := range globalDirs {
+		name := filepath.Base(globalDir)
+		input, err := os.ReadFile(filepath.Join("testdata/input", name+".comp"))
+		require.Nil(s.T(), err)
+
+		comp := New()
+		comp.SetStore(store.NewFS(os.DirFS(globalDir)))
+
+		var buf bytes.Buffer
+		err = comp.Convert([]byte(strings.TrimSpace(string(input))), &buf)
+		assert.Nil(s.T(), err)
+
+		golden, err := os.ReadFile(filepath.Join("testdata/output", name+".golden"))
+		require.Nil(s.T(), err, "golden file missing")
+
+		assert.Equal(s.T(), strings.TrimSpace(string(golden)), buf.String(), "from %s", globalDir)
+	}
+}
+
+func (s *componoTestSuite) TestASTRoundTrip() {
+	inputFiles, err := filepath.Glob("testdata/input/*.comp")
+	require.Nil(s.T(), err)
+
+	marshalers := map[string]struct {
+		marshal   func(ast.Node) ([]byte, error)
+		unmarshal func([]byte) (ast.Node, error)
+	}{
+		"json":   {ast.MarshalJSON, ast.UnmarshalJSON},
+		"binary": {ast.MarshalBinary, ast.UnmarshalBinary},
+	}
+
+	for _, inputPath := range inputFiles {
+		name := filepath.Base(inputPath)
+		input, err := os.ReadFile(inputPath)
+		require.Nil(s.T(), err)
+		source := []byte(strings.TrimSpace(string(input)))
+		if len(source) == 0 {
+			continue
+		}
+
+		comp := s.newGoldenCompono(name, func(Compono) {}).(*compono)
+
+		var want bytes.Buffer
+		assert.Nil(s.T(), comp.Convert(source, &want))
+
+		for format, m := range marshalers {
+			root, err := comp.parseRoot(source)
+			require.Nil(s.T(), err)
+
+			data, err := m.marshal(root)
+			require.Nil(s.T(), err, "%s from %s", format, inputPath)
+			restored, err := m.unmarshal(data)
+			require.Nil(s.T(), err, "%s from %s", format, inputPath)
+
+			s.assertSameTree(root, restored)
+
+			var got bytes.Buffer
+			assert.Nil(s.T(), comp.wrapAndRender(restored, &got, comp.newConvertConfig(nil)))
+			assert.Equal(s.T(), want.String(), got.String(), "%s from %s", format, inputPath)
+		}
+	}
+}
+
+func (s *componoTestSuite) TestASTUnmarshalInvalid() {
+	root := New().Parser().Parse([]byte("# Hello {{ NAME }}"), ast.DefaultRootNode())
+
+	data, err := ast.MarshalBinary(root)
+	require.Nil(s.T(), err)
+	for _, n := range []int{0, 4, len(data) / 2, len(data) - 1} {
+		_, err = ast.UnmarshalBinary(data[:n])
+		assert.NotNil(s.T(), err, "from %d bytes", n)
+	}
+	_, err = ast.UnmarshalBinary(append(data, 0))
+	assert.NotNil(s.T(), err)
+
+	_, err = ast.UnmarshalJSON([]byte(`{"version":2,"sources":[],"root":{}}`))
+	assert.NotNil(s.T(), err)
+	_, err = ast.UnmarshalJSON([]byte(`{"version":1,"sources":[],"root":{"rule":"p","source":0}}`))
+	assert.NotNil(s.T(), err)
+
+	_, err = ast.MarshalJSON(New().Parser().Parse([]byte("\xff"), ast.DefaultRootNode()))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *componoTestSuite) assertSameTree(want, got ast.Node) {
+	assert.Equal(s.T(), want.Rule().Name(), got.Rule().Name())
+	assert.Equal(s.T(), want.Raw(), got.Raw())
+	assert.Equal(s.T(), want.Range(), got.Range())
+	require.Len(s.T(), got.Children(), len(want.Children()))
+	for i, child := range got.Children() {
+		assert.Equal(s.T(), got, child.Parent())
+		s.assertSameTree(want.Children()[i], child)
 	}
 }
 
@@ -108,6 +302,930 @@ func (s *componoTestSuite) TestUnregisterGlobalComponent() {
   assert.Equal(s.T(), 0, len(compono.globalWrapper.Children()))
 }
 
+func (s *componoTestSuite) TestGlobalComponentPositions() {
+	compono := New().(*compono)
+	err := compono.RegisterGlobalComponent("GREETING", []byte("name=\"\"\n# Hello {{ name }}"))
+	require.Nil(s.T(), err)
+
+	paramRefs := ast.FilterNodesInTree(compono.globalWrapper, func(n ast.Node) bool {
+		return ast.IsRuleName(n, "param-ref")
+	})
+	require.Len(s.T(), paramRefs, 1)
+
+	assert.Equal(s.T(), ast.Range{
+		Start: ast.Position{Source: "GREETING", Offset: 16, Line: 2, Column: 9},
+		End:   ast.Position{Source: "GREETING", Offset: 26, Line: 2, Column: 19},
+	}, paramRefs[0].Range())
+}
+
+func (s *componoTestSuite) TestStrictMode() {
+	source := []byte("{{ UNKNOWN }}\n\n{{ GREETING name=5 }}\n\n~ GREETING name=\"\"\nHello {{ name }}")
+
+	comp := New()
+	comp.SetStrict(true)
+
+	var buf bytes.Buffer
+	err := comp.Convert(source, &buf)
+	require.NotNil(s.T(), err)
+	assert.Empty(s.T(), buf.String())
+
+	var componoErr *ComponoError
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrWrapped, componoErr.Code)
+	require.Len(s.T(), componoErr.Diagnostics, 2)
+
+	assert.Equal(s.T(), errwrap.CodeUnknownComp, componoErr.Diagnostics[0].Code)
+	assert.Equal(s.T(), 1, componoErr.Diagnostics[0].Range.Start.Line)
+	assert.Equal(s.T(), errwrap.CodeWrongArgType, componoErr.Diagnostics[1].Code)
+	assert.Equal(s.T(), 3, componoErr.Diagnostics[1].Range.Start.Line)
+	assert.Equal(s.T(), "3:1: Wrong argument type: The parameter name has the wrong type.", componoErr.Diagnostics[1].Error())
+
+	var diagnostic *errwrap.Diagnostic
+	assert.True(s.T(), errors.As(err, &diagnostic))
+
+	err = comp.Convert(source, &buf, WithStrict(false))
+	assert.Nil(s.T(), err)
+	assert.Contains(s.T(), buf.String(), "<compono-error-block>")
+
+	buf.Reset()
+	err = New().Convert([]byte("{{ GREETING }}\n\n~ GREETING\nHello\n\n~ UNUSED\n{{ UNKNOWN }}"), &buf, WithStrict(true))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "<p>Hello</p>", buf.String())
+}
+
+func (s *componoTestSuite) TestErrorModes() {
+	source := []byte("Hello {{ UNKNOWN }}\n\n{{ GREETING name=5 }}\n\n~ GREETING name=\"\"\nHi {{ name }}")
+
+	cases := []struct {
+		mode     html.ErrorMode
+		expected string
+	}{
+		{
+			mode: html.ErrorModeDiv,
+			expected: `<p>Hello <span class="compono-error" data-code="unknown-comp"><strong class="compono-error-title">Unknown component</strong> The component <strong>UNKNOWN</strong> is not defined or not registered.</span></p>` +
+				`<div class="compono-error" data-code="wrong-arg-type"><strong class="compono-error-title">Wrong argument type</strong> The parameter <strong>name</strong> has the wrong type.</div>`,
+		},
+		{
+			mode: html.ErrorModeComment,
+			expected: `<p>Hello <!-- compono unknown-comp: Unknown component: The component UNKNOWN is not defined or not registered. --></p>` +
+				`<!-- compono wrong-arg-type: Wrong argument type: The parameter name has the wrong type. -->`,
+		},
+		{
+			mode:     html.ErrorModeSuppress,
+			expected: `<p>Hello </p>`,
+		},
+	}
+
+	for _, tc := range cases {
+		r := html.NewRenderer(logger.NewLogger())
+		r.SetErrorRenderer(html.NewErrorRenderer(tc.mode))
+
+		comp := New()
+		comp.SetRenderer(r)
+
+		var buf bytes.Buffer
+		require.Nil(s.T(), comp.Convert(source, &buf))
+		assert.Equal(s.T(), tc.expected, buf.String())
+	}
+}
+
+func (s *componoTestSuite) TestLocalizedErrors() {
+	source := []byte("{{ UNKNOWN }}")
+
+	comp := New()
+	comp.SetStrict(true)
+	comp.SetLocale("tr")
+
+	err := comp.Convert(source, &bytes.Buffer{})
+	var componoErr *ComponoError
+	require.True(s.T(), errors.As(err, &componoErr))
+	require.Len(s.T(), componoErr.Diagnostics, 1)
+
+	d := componoErr.Diagnostics[0]
+	assert.Equal(s.T(), "Bilinmeyen bileşen", d.Title)
+	assert.Equal(s.T(), errwrap.MsgUnknownComp, d.MessageID)
+	assert.Equal(s.T(), "UNKNOWN bileşeni tanımlı veya kayıtlı değil.", d.Message)
+	assert.Equal(s.T(), []string{"UNKNOWN"}, d.Components())
+
+	err = comp.Convert(source, &bytes.Buffer{}, WithLocale("de-AT"))
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), "Unbekannte Komponente", componoErr.Diagnostics[0].Title)
+
+	comp.ErrorWrapper().SetCatalog(errwrap.MapCatalog{
+		"xx": {errwrap.MsgUnknownComp: "{0}?"},
+	})
+
+	err = comp.Convert(source, &bytes.Buffer{}, WithLocale("xx"))
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), "Unknown component", componoErr.Diagnostics[0].Title)
+	assert.Equal(s.T(), errwrap.Message{{Kind: errwrap.ComponentPart, Text: "UNKNOWN"}, {Kind: errwrap.TextPart, Text: "?"}}, componoErr.Diagnostics[0].Parts)
+}
+
+func (s *componoTestSuite) TestSuggestions() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("PRODUCT_CARD", []byte("Product")))
+
+	err := comp.Convert([]byte("{{ PRODUCT_CRAD }}\n\n{{ LNK }}\n\n{{ FOOTER }}"), &bytes.Buffer{}, WithStrict(true))
+	var componoErr *ComponoError
+	require.True(s.T(), errors.As(err, &componoErr))
+	require.Len(s.T(), componoErr.Diagnostics, 3)
+
+	assert.Equal(s.T(), []string{"PRODUCT_CARD"}, componoErr.Diagnostics[0].Suggestions)
+	assert.Equal(s.T(), "The component PRODUCT_CRAD is not defined or not registered. Did you mean PRODUCT_CARD?", componoErr.Diagnostics[0].Message)
+	assert.Equal(s.T(), []string{"LINK"}, componoErr.Diagnostics[1].Suggestions)
+	assert.Empty(s.T(), componoErr.Diagnostics[2].Suggestions)
+}
+
+func (s *componoTestSuite) TestLint() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("FOOTER", []byte("Footer")))
+
+	source := []byte("{{ CARD }}\n\n{{ FOOTER }}\n\n{{ UNKNOWN }}\n\n" +
+		"~ CARD title=\"\" unused=\"\"\n{{ title }}\n\n" +
+		"~ CARD\nAgain\n\n" +
+		"~ FOOTER\nLocal footer\n\n" +
+		"~ LINK text=\"\"\n{{ text }}")
+
+	diagnostics, err := comp.Lint(source)
+	require.Nil(s.T(), err)
+
+	type found struct {
+		severity errwrap.Severity
+		code     errwrap.Code
+		line     int
+	}
+	got := []found{}
+	for _, d := range diagnostics {
+		got = append(got, found{d.Severity, d.Code, d.Range.Start.Line})
+	}
+
+	assert.Equal(s.T(), []found{
+		{errwrap.SeverityError, errwrap.CodeUnknownComp, 5},
+		{errwrap.SeverityWarning, errwrap.CodeUnusedParam, 7},
+		{errwrap.SeverityWarning, errwrap.CodeDuplicateComp, 10},
+		{errwrap.SeverityWarning, errwrap.CodeShadowedComp, 13},
+		{errwrap.SeverityWarning, errwrap.CodeShadowedComp, 16},
+		{errwrap.SeverityWarning, errwrap.CodeUnusedComp, 16},
+	}, got)
+	assert.Equal(s.T(), "The parameter unused is never used in component CARD.", diagnostics[1].Message)
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert(source, &buf))
+	assert.NotContains(s.T(), buf.String(), "compono-warning")
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert(source, &buf, WithWarnings(true)))
+	assert.Contains(s.T(), buf.String(), `<compono-warning-block><div slot="title">Unused parameter</div>`)
+
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD_LIST", []byte("title=\"\" unused=\"\"\n{{ ITEM }}\n\n~ ITEM\n{{ title }}\n\n~ SPARE\nSpare\n\n~ FOOTER\nLocal footer")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("NOT_RENDERED", []byte("unused=\"\"\nText")))
+
+	diagnostics, err = comp.Lint([]byte("{{ CARD_LIST }}"))
+	require.Nil(s.T(), err)
+	got = []found{}
+	for _, d := range diagnostics {
+		assert.Equal(s.T(), "CARD_LIST", d.Range.Start.Source)
+		got = append(got, found{d.Severity, d.Code, d.Range.Start.Line})
+	}
+	assert.Equal(s.T(), []found{
+		{errwrap.SeverityWarning, errwrap.CodeUnusedParam, 1},
+		{errwrap.SeverityWarning, errwrap.CodeUnusedComp, 7},
+		{errwrap.SeverityWarning, errwrap.CodeShadowedComp, 10},
+		{errwrap.SeverityWarning, errwrap.CodeUnusedComp, 10},
+	}, got)
+	assert.Equal(s.T(), "The parameter unused is never used in component CARD_LIST.", diagnostics[0].Message)
+}
+
+func (s *componoTestSuite) TestRegisterGlobalComponentWithValidation() {
+	comp := New()
+
+	err := comp.RegisterGlobalComponent("CARD", []byte("title=\"\"\n{{ titel }} {{ LINK text=\"Home\" url=\"/\" }}\n\n{{ MISSING }}"), WithValidation(true))
+	var componoErr *ComponoError
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrInvalidGlobal, componoErr.Code)
+	require.Len(s.T(), componoErr.Diagnostics, 2)
+	assert.Equal(s.T(), errwrap.CodeUnknownParam, componoErr.Diagnostics[0].Code)
+	assert.Equal(s.T(), "CARD", componoErr.Diagnostics[0].Range.Start.Source)
+	assert.Equal(s.T(), 2, componoErr.Diagnostics[0].Range.Start.Line)
+	assert.Equal(s.T(), errwrap.CodeUnknownComp, componoErr.Diagnostics[1].Code)
+	assert.Equal(s.T(), "CARD", componoErr.Diagnostics[1].Range.Start.Source)
+	assert.Nil(s.T(), comp.(*compono).getGlobalCompDefByName("CARD"))
+
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("{{ MISSING }}")))
+
+	require.Nil(s.T(), comp.RegisterGlobalComponent("HEADER", []byte("{{ NAV }}"), WithValidation(false)))
+	err = comp.RegisterGlobalComponent("NAV", []byte("{{ HEADER }}"), WithValidation(true))
+	require.True(s.T(), errors.As(err, &componoErr))
+	require.NotEmpty(s.T(), componoErr.Diagnostics)
+	assert.Equal(s.T(), "HEADER:1:1: Infinite component call: The call to component NAV creates an infinite loop and was skipped.", componoErr.Diagnostics[0].Error())
+
+	assert.Nil(s.T(), comp.RegisterGlobalComponent("NAV", []byte("[Home](/)"), WithValidation(true)))
+}
+
+func (s *componoTestSuite) TestUpsertGlobalComponent() {
+	comp := New()
+	require.Nil(s.T(), comp.UpsertGlobalComponent("FOOTER", []byte("Old footer")))
+	require.Nil(s.T(), comp.UpsertGlobalComponent("FOOTER", []byte("New footer")))
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("{{ FOOTER }}"), &buf))
+	assert.Equal(s.T(), "<p>New footer</p>", buf.String())
+
+	err := comp.UpsertGlobalComponent("FOOTER", []byte("{{ MISSING }}"), WithValidation(true))
+	var componoErr *ComponoError
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrInvalidGlobal, componoErr.Code)
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ FOOTER }}"), &buf))
+	assert.Equal(s.T(), "<p>New footer</p>", buf.String())
+}
+
+func (s *componoTestSuite) TestReplaceGlobalComponents() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("OLD", []byte("Old")))
+
+	err := comp.ReplaceGlobalComponents(map[string][]byte{
+		"HEADER": []byte("{{ NAV }}"),
+		"NAV":    []byte("{{ HEADER }}"),
+	}, WithValidation(true))
+	var componoErr *ComponoError
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrInvalidGlobal, componoErr.Code)
+
+	err = comp.ReplaceGlobalComponents(map[string][]byte{"bad name": []byte("x")})
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrInvalidGlobalName, componoErr.Code)
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("{{ OLD }}"), &buf))
+	assert.Equal(s.T(), "<p>Old</p>", buf.String())
+
+	require.Nil(s.T(), comp.ReplaceGlobalComponents(map[string][]byte{
+		"HEADER": []byte("# {{ NAV }}"),
+		"NAV":    []byte("Home"),
+	}, WithValidation(true)))
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ HEADER }}\n\n{{ OLD }}"), &buf))
+	assert.Equal(s.T(), "<h1>Home</h1>", buf.String()[:len("<h1>Home</h1>")])
+	assert.Contains(s.T(), buf.String(), "<compono-error-block>")
+}
+
+func (s *componoTestSuite) TestReplaceGlobalComponentsDuringConversions() {
+	comp := New()
+	themes := []map[string][]byte{
+		{"HEADER": []byte("A"), "FOOTER": []byte("A")},
+		{"HEADER": []byte("B"), "FOOTER": []byte("B")},
+	}
+	require.Nil(s.T(), comp.ReplaceGlobalComponents(themes[0]))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var buf bytes.Buffer
+				assert.Nil(s.T(), comp.Convert([]byte("{{ HEADER }} {{ FOOTER }}"), &buf))
+				assert.Contains(s.T(), []string{"<p>A A</p>", "<p>B B</p>"}, buf.String())
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		assert.Nil(s.T(), comp.ReplaceGlobalComponents(themes[j%2]))
+	}
+	wg.Wait()
+}
+
+func (s *componoTestSuite) TestConversionsInParallel() {
+	comp := New()
+	titles := map[string]string{"en": "Unknown component", "tr": "Bilinmeyen bileşen", "de": "Unbekannte Komponente"}
+
+	var wg sync.WaitGroup
+	for locale, title := range titles {
+		wg.Add(1)
+		go func(locale, title string) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				var buf bytes.Buffer
+				assert.Nil(s.T(), comp.Convert([]byte("{{ MISSING }}"), &buf, WithLocale(locale)))
+				assert.Contains(s.T(), buf.String(), title)
+
+				diagnostics, err := comp.Lint([]byte("{{ MISSING }}"), WithLocale(locale))
+				assert.Nil(s.T(), err)
+				if assert.Len(s.T(), diagnostics, 1) {
+					assert.Equal(s.T(), title, diagnostics[0].Title)
+				}
+			}
+		}(locale, title)
+	}
+	wg.Wait()
+}
+
+func (s *componoTestSuite) TestStoreParseCache() {
+	memory := store.NewMemory()
+	memory.Set("HEADER", []byte("# {{ NAV }}"))
+	memory.Set("NAV", []byte("Home"))
+
+	comp := New()
+	comp.SetStore(memory)
+	require.Nil(s.T(), comp.RegisterGlobalComponent("FOOTER", []byte("Registered")))
+	memory.Set("FOOTER", []byte("Stored"))
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("{{ HEADER }}\n\n{{ FOOTER }}"), &buf))
+	assert.Equal(s.T(), "<h1>Home</h1><p>Registered</p>", buf.String())
+
+	cached := comp.(*compono).storeCache["NAV"].node
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ HEADER }}"), &buf))
+	assert.Same(s.T(), cached, comp.(*compono).storeCache["NAV"].node)
+
+	memory.Set("NAV", []byte("Start"))
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ HEADER }}"), &buf))
+	assert.Equal(s.T(), "<h1>Start</h1>", buf.String())
+	assert.NotSame(s.T(), cached, comp.(*compono).storeCache["NAV"].node)
+
+	memory.Delete("NAV")
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ HEADER }}"), &buf))
+	assert.Contains(s.T(), buf.String(), "<compono-error-inline>")
+}
+
+func (s *componoTestSuite) TestSetStoreDuringConversions() {
+	memory := store.NewMemory()
+	memory.Set("HEADER", []byte("Stored"))
+
+	comp := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var buf bytes.Buffer
+				assert.Nil(s.T(), comp.Convert([]byte("{{ HEADER }}"), &buf))
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		if j%2 == 0 {
+			comp.SetStore(memory)
+		} else {
+			comp.SetStore(nil)
+		}
+	}
+	wg.Wait()
+}
+
+// blockingStore holds every lookup until release is closed, reporting each
+// one on started.
+type blockingStore struct {
+	*store.Memory
+	started chan string
+	release chan struct{}
+}
+
+func (bs *blockingStore) Lookup(name string) (store.Component, bool, error) {
+	bs.started <- name
+	<-bs.release
+	return bs.Memory.Lookup(name)
+}
+
+func (s *componoTestSuite) TestStoreLookupsInParallel() {
+	memory := store.NewMemory()
+	memory.Set("HEADER", []byte("Header"))
+	memory.Set("FOOTER", []byte("Footer"))
+	bs := &blockingStore{Memory: memory, started: make(chan string, 2), release: make(chan struct{})}
+
+	comp := New()
+	comp.SetStore(bs)
+
+	var wg sync.WaitGroup
+	for name, want := range map[string]string{"HEADER": "<p>Header</p>", "FOOTER": "<p>Footer</p>"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			assert.Nil(s.T(), comp.Convert([]byte("{{ "+name+" }}"), &buf))
+			assert.Equal(s.T(), want, buf.String())
+		}()
+	}
+
+	// both lookups must be in flight at once
+	for i := 0; i < 2; i++ {
+		select {
+		case <-bs.started:
+		case <-time.After(5 * time.Second):
+			s.T().Fatal("store lookups did not run in parallel")
+		}
+	}
+	close(bs.release)
+	wg.Wait()
+}
+
+func (s *componoTestSuite) TestDependencies() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("PAGE", []byte("{{ HEADER }}\n\n{{ CARD wrapper=FRAME }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("HEADER", []byte("# {{ NAV }} {{ LINK text=\"Home\" url=\"/\" }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("NAV", []byte("Nav")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("wrapper=BOX\n{{ wrapper }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("BOX", []byte("{{ ITEM }}\n\n~ ITEM\nItem")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("FRAME", []byte("Frame")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("ITEM", []byte("Global item")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("UNUSED", []byte("{{ NAV }}")))
+
+	memory := store.NewMemory()
+	memory.Set("SIDEBAR", []byte("{{ NAV }}"))
+	comp.SetStore(memory)
+
+	deps, err := comp.Dependencies([]byte("{{ PAGE }}\n\n{{ LOCAL }}\n\n~ LOCAL\n{{ SIDEBAR }}"))
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"BOX", "CARD", "FRAME", "HEADER", "NAV", "PAGE", "SIDEBAR"}, deps)
+
+	dependents, err := comp.Dependents("NAV")
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"HEADER", "PAGE", "SIDEBAR", "UNUSED"}, dependents)
+
+	dependents, err = comp.Dependents("ITEM")
+	require.Nil(s.T(), err)
+	assert.Empty(s.T(), dependents)
+
+	dependents, err = comp.Dependents("FRAME")
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"PAGE"}, dependents)
+}
+
+func (s *componoTestSuite) TestComponents() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("PAGE", []byte("title=\"Home\" wrapper=CARD\n# {{ title }}\n\n{{ wrapper }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("Card")))
+
+	memory := store.NewMemory()
+	memory.Set("BANNER", []byte("count=3 visible=false\nBanner"))
+	comp.SetStore(memory)
+
+	infos, err := comp.Components([]byte("{{ USER }}\n\n~ USER name=\"\"\n**{{ name }}**\n\n~ USER\nDuplicate"))
+	require.Nil(s.T(), err)
+
+	assert.Equal(s.T(), []ComponentInfo{
+		{Name: "LINK", Kind: KindBuiltin, Description: "Creates an anchor element.", InlineRenderable: true, Params: []ParamInfo{
+			{Name: "text", Type: ParamString, Default: "", Description: "Text of the link."},
+			{Name: "url", Type: ParamString, Default: "", Description: "Address the link points to."},
+			{Name: "new-tab", Type: ParamBool, Default: false, Description: "Opens the link in a new tab."},
+		}},
+		{Name: "CONTENT", Kind: KindBuiltin, Description: "Renders the content of the page or layout that uses the current layout.", InlineRenderable: false, Params: []ParamInfo{}},
+		{Name: "BANNER", Kind: KindGlobal, InlineRenderable: true, Params: []ParamInfo{
+			{Name: "count", Type: ParamNumber, Default: float64(3)},
+			{Name: "visible", Type: ParamBool, Default: false},
+		}},
+		{Name: "CARD", Kind: KindGlobal, InlineRenderable: true, Params: []ParamInfo{}},
+		{Name: "PAGE", Kind: KindGlobal, InlineRenderable: false, Params: []ParamInfo{
+			{Name: "title", Type: ParamString, Default: "Home"},
+			{Name: "wrapper", Type: ParamComponent, Default: "CARD"},
+		}},
+		{Name: "USER", Kind: KindLocal, InlineRenderable: true, Params: []ParamInfo{
+			{Name: "name", Type: ParamString, Default: ""},
+		}},
+	}, infos)
+
+	data, err := json.Marshal(infos[len(infos)-1])
+	require.Nil(s.T(), err)
+	assert.JSONEq(s.T(), `{"name":"USER","kind":"local","inlineRenderable":true,"params":[{"name":"name","type":"string","default":""}]}`, string(data))
+
+	infos, err = comp.Components(nil)
+	require.Nil(s.T(), err)
+	assert.Len(s.T(), infos, 5)
+}
+
+func (s *componoTestSuite) TestCommentsInParagraphs() {
+	comp := New()
+	tests := map[string]string{
+		"One\n%% a\n%% b\nTwo":    "<p>One<br>Two</p>",
+		"One\n%% a\n\nTwo":        "<p>One</p><p>Two</p>",
+		"# Title\n%% a\nText":     "<h1>Title</h1><p>Text</p>",
+		"# Title\n%% a\n## Sub":   "<h1>Title</h1><h2>Sub</h2>",
+		"Text\n%% a":              "<p>Text</p>",
+		"%% a\n{{ X }}\n\n~ X\nX": "<p>X</p>",
+	}
+
+	for source, want := range tests {
+		var buf bytes.Buffer
+		require.Nil(s.T(), comp.Convert([]byte(source), &buf))
+		assert.Equal(s.T(), want, buf.String(), source)
+	}
+}
+
+func (s *componoTestSuite) TestComponentDocs() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("BANNER", []byte("%% Site-wide banner.\n%% @param text Banner text.\n\ntext=\"Sale\"\n**{{ text }}**")))
+
+	infos, err := comp.Components([]byte("{{ CARD }}\n%% Card for the home page.\n%% Keep it short.\n%% @param title Card title.\n~ CARD title=\"\" size=1\n## {{ title }} {{ size }}\n\n%% Not directly above.\n\n~ NOTE\n%% only a note\nNote\n%% A tip.\n~ TIP\nTip"))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), infos, 6)
+
+	assert.Equal(s.T(), ComponentInfo{Name: "BANNER", Kind: KindGlobal, Description: "Site-wide banner.", InlineRenderable: true, Params: []ParamInfo{
+		{Name: "text", Type: ParamString, Default: "Sale", Description: "Banner text."},
+	}}, infos[2])
+	assert.Equal(s.T(), ComponentInfo{Name: "CARD", Kind: KindLocal, Description: "Card for the home page.\nKeep it short.", InlineRenderable: false, Params: []ParamInfo{
+		{Name: "title", Type: ParamString, Default: "", Description: "Card title."},
+		{Name: "size", Type: ParamNumber, Default: float64(1)},
+	}}, infos[3])
+	assert.Equal(s.T(), ComponentInfo{Name: "NOTE", Kind: KindLocal, InlineRenderable: true, Params: []ParamInfo{}}, infos[4])
+	assert.Equal(s.T(), ComponentInfo{Name: "TIP", Kind: KindLocal, Description: "A tip.", InlineRenderable: true, Params: []ParamInfo{}}, infos[5])
+}
+
+func (s *componoTestSuite) TestLayouts() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("LOOP_A", []byte("~~ LAYOUT LOOP_B\n{{ CONTENT }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("LOOP_B", []byte("~~ LAYOUT LOOP_A\n{{ CONTENT }}")))
+
+	memory := store.NewMemory()
+	memory.Set("BASE", []byte("# Site\n\n{{ CONTENT }}"))
+	memory.Set("POST", []byte("~~ LAYOUT BASE\n## Post\n\n{{ CONTENT }}"))
+	comp.SetStore(memory)
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("~~ LAYOUT POST\nBody"), &buf))
+	assert.Equal(s.T(), "<h1>Site</h1><h2>Post</h2><p>Body</p>", buf.String())
+
+	deps, err := comp.Dependencies([]byte("~~ LAYOUT POST\nBody"))
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"BASE", "POST"}, deps)
+
+	dependents, err := comp.Dependents("BASE")
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"POST"}, dependents)
+
+	buf.Reset()
+	err = comp.Convert([]byte("~~ LAYOUT LOOP_A\nBody"), &buf, WithStrict(true))
+	var componoErr *ComponoError
+	require.ErrorAs(s.T(), err, &componoErr)
+	require.Len(s.T(), componoErr.Diagnostics, 1)
+	assert.Equal(s.T(), errwrap.CodeInfiniteCompCall, componoErr.Diagnostics[0].Code)
+	assert.Equal(s.T(), "LOOP_A", componoErr.Diagnostics[0].Range.Start.Source)
+
+	buf.Reset()
+	err = comp.Convert([]byte("Body\n\n{{ CONTENT }}\n\n{{ BASE }}"), &buf, WithStrict(true))
+	require.ErrorAs(s.T(), err, &componoErr)
+	require.Len(s.T(), componoErr.Diagnostics, 1)
+	assert.Equal(s.T(), errwrap.CodeInvalidCompUsage, componoErr.Diagnostics[0].Code)
+	assert.Equal(s.T(), "CONTENT only renders inside a layout and renders nothing here.", componoErr.Diagnostics[0].Message)
+
+	buf.Reset()
+	err = comp.Convert([]byte("~~ LAYOUT BASE\n~~ LAYOUT POST\nBody\n\n~~ LAYOUT BASE"), &buf, WithStrict(true))
+	require.ErrorAs(s.T(), err, &componoErr)
+	require.Len(s.T(), componoErr.Diagnostics, 2)
+	assert.Equal(s.T(), errwrap.CodeInvalidCompUsage, componoErr.Diagnostics[0].Code)
+	assert.Equal(s.T(), "Only the first layout declaration is used, so the layout POST is ignored.", componoErr.Diagnostics[0].Message)
+	assert.Equal(s.T(), 5, componoErr.Diagnostics[1].Range.Start.Line)
+
+	buf.Reset()
+	err = comp.Convert([]byte("Body\n\n~~ LAYOUT BASE"), &buf, WithStrict(true))
+	require.ErrorAs(s.T(), err, &componoErr)
+	require.Len(s.T(), componoErr.Diagnostics, 1)
+	assert.Equal(s.T(), "The layout BASE must be declared on the first line and is ignored.", componoErr.Diagnostics[0].Message)
+
+	require.Nil(s.T(), comp.RegisterGlobalComponent("TWICE", []byte("{{ CONTENT }}\n\n{{ CONTENT }}")))
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("~~ LAYOUT TWICE\nBody"), &buf))
+	assert.Equal(s.T(), 1, strings.Count(buf.String(), "<p>Body</p>"))
+	assert.Contains(s.T(), buf.String(), "can be used only once in a layout")
+}
+
+func (s *componoTestSuite) TestNamespaces() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("Shop card"), WithNamespace("SHOP")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("LIST", []byte("{{ CARD }}"), WithNamespace("SHOP")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("Plain card")))
+
+	var componoErr *ComponoError
+	err := comp.RegisterGlobalComponent("card", []byte("x"), WithNamespace("SHOP"))
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrInvalidGlobalName, componoErr.Code)
+	err = comp.RegisterGlobalComponent("CARD", []byte("x"), WithNamespace("shop"))
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrInvalidGlobalName, componoErr.Code)
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("{{ SHOP.LIST }}\n\n{{ CARD }}"), &buf))
+	assert.Equal(s.T(), "<p>Shop card</p><p>Plain card</p>", buf.String())
+
+	deps, err := comp.Dependencies([]byte("{{ SHOP.LIST }}"))
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"SHOP.CARD", "SHOP.LIST"}, deps)
+
+	require.Nil(s.T(), comp.ReplaceGlobalComponents(map[string][]byte{
+		"CARD": []byte("New shop card"),
+	}, WithNamespace("SHOP")))
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ SHOP.CARD }}\n\n{{ CARD }}\n\n{{ SHOP.LIST }}"), &buf))
+	assert.Contains(s.T(), buf.String(), "<p>New shop card</p><p>Plain card</p>")
+	assert.Contains(s.T(), buf.String(), "Unknown component")
+
+	comp = New()
+	comp.SetStore(store.NewFS(fstest.MapFS{
+		"SHOP/CARD.comp": {Data: []byte("Stored shop card")},
+		"SHOP/LIST.comp": {Data: []byte("{{ CARD }}")},
+	}))
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ SHOP.LIST }}"), &buf))
+	assert.Equal(s.T(), "<p>Stored shop card</p>", buf.String())
+}
+
+func (s *componoTestSuite) TestRename() {
+	comp := New()
+
+	source := "{{ CARD }} and {{CARD  x=1}}\n\n{{ BOX wrapper=CARD }}\n\n```\n{{ CARD }}\n```\n\n%% {{ CARD }}\n\n`{{ CARD }}`\n\n~ BOX wrapper=CARD\n{{ wrapper }}\n\n~ CARDS\nx"
+	renamed, err := comp.Rename([]byte(source), "CARD", "PANEL")
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "{{ PANEL }} and {{PANEL  x=1}}\n\n{{ BOX wrapper=PANEL }}\n\n```\n{{ CARD }}\n```\n\n%% {{ CARD }}\n\n`{{ CARD }}`\n\n~ BOX wrapper=PANEL\n{{ wrapper }}\n\n~ CARDS\nx", string(renamed))
+
+	renamed, err = comp.Rename([]byte("~~ LAYOUT BASE\n{{ BOX }}\n\n~ BOX\nBox {{ BOX_ITEM }}"), "BOX", "FRAME")
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "~~ LAYOUT BASE\n{{ FRAME }}\n\n~ FRAME\nBox {{ BOX_ITEM }}", string(renamed))
+
+	renamed, err = comp.Rename([]byte("~~ LAYOUT BASE\nBody"), "BASE", "SITE.BASE")
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "~~ LAYOUT SITE.BASE\nBody", string(renamed))
+
+	var componoErr *ComponoError
+	_, err = comp.Rename([]byte("{{ BOX }}\n\n~ BOX\nBox"), "BOX", "UI.FRAME")
+	require.ErrorAs(s.T(), err, &componoErr)
+	assert.Equal(s.T(), ErrInvalidGlobalName, componoErr.Code)
+	_, err = comp.Rename([]byte("{{ BOX }}"), "BOX", "frame")
+	require.ErrorAs(s.T(), err, &componoErr)
+	assert.Equal(s.T(), ErrInvalidGlobalName, componoErr.Code)
+}
+
+func (s *componoTestSuite) TestRenameGlobalComponent() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("Shop card"), WithNamespace("SHOP")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("LIST", []byte("{{ CARD }}"), WithNamespace("SHOP")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("Plain card")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("PAGE", []byte("~~ LAYOUT BASE\n{{ SHOP.CARD }} {{ CARD }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("BASE", []byte("# Site\n\n{{ CONTENT }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("BOX", []byte("wrapper=CARD\n{{ wrapper }} {{ CARD }}\n\n~ CARD\nLocal card")))
+
+	require.Nil(s.T(), comp.RenameGlobalComponent("SHOP.CARD", "SHOP.TILE"))
+	require.Nil(s.T(), comp.RenameGlobalComponent("CARD", "UI.PANEL"))
+	require.Nil(s.T(), comp.RenameGlobalComponent("BASE", "SITE"))
+
+	sources := map[string]string{}
+	for _, globalCompDef := range comp.(*compono).globalWrapper.Children() {
+		sources[globalCompName(globalCompDef)] = string(globalCompDef.Source().Content())
+	}
+	assert.Equal(s.T(), map[string]string{
+		"SHOP.TILE": "Shop card",
+		"SHOP.LIST": "{{ TILE }}",
+		"UI.PANEL":  "Plain card",
+		"PAGE":      "~~ LAYOUT SITE\n{{ SHOP.TILE }} {{ UI.PANEL }}",
+		"SITE":      "# Site\n\n{{ CONTENT }}",
+		"BOX":       "wrapper=CARD\n{{ wrapper }} {{ CARD }}\n\n~ CARD\nLocal card",
+	}, sources)
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("{{ PAGE }}\n\n{{ SHOP.LIST }}"), &buf))
+	assert.Equal(s.T(), "<h1>Site</h1><p>Shop card Plain card</p><p>Shop card</p>", buf.String())
+
+	var componoErr *ComponoError
+	err := comp.RenameGlobalComponent("CARD", "TILE")
+	require.ErrorAs(s.T(), err, &componoErr)
+	assert.Equal(s.T(), ErrGlobalNotExist, componoErr.Code)
+	err = comp.RenameGlobalComponent("PAGE", "SITE")
+	require.ErrorAs(s.T(), err, &componoErr)
+	assert.Equal(s.T(), ErrGlobalAlreadyRegistered, componoErr.Code)
+	err = comp.RenameGlobalComponent("PAGE", "page")
+	require.ErrorAs(s.T(), err, &componoErr)
+	assert.Equal(s.T(), ErrInvalidGlobalName, componoErr.Code)
+}
+
+func (s *componoTestSuite) TestURLPolicy() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("NAV", []byte("{{ LINK text=\"x\" url=\"data:text/html,x\" }}")))
+	source := []byte("[a](javascript:void) [b](/b)\n\n{{ NAV }}")
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert(source, &buf))
+	assert.Equal(s.T(), `<p><a href="#">a</a> <a href="/b">b</a></p><a href="#">x</a>`, buf.String())
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert(source, &buf, WithURLPolicy(nil)))
+	assert.Equal(s.T(), `<p><a href="javascript:void">a</a> <a href="/b">b</a></p><a href="data:text/html,x">x</a>`, buf.String())
+
+	comp.SetURLPolicy(&urlpolicy.Policy{Schemes: []string{"https"}, Action: urlpolicy.Report})
+
+	diagnostics, err := comp.Lint(source)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), diagnostics, 3)
+	for _, diagnostic := range diagnostics {
+		assert.Equal(s.T(), errwrap.CodeUnsafeURL, diagnostic.Code)
+	}
+	assert.Equal(s.T(), "The URL javascript:void is not allowed by the URL policy.", diagnostics[0].Message)
+	assert.Equal(s.T(), "NAV", diagnostics[2].Range.Start.Source)
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert(source, &buf))
+	assert.Contains(s.T(), buf.String(), "<compono-error-inline>")
+	assert.NotContains(s.T(), buf.String(), `href="javascript:`)
+	assert.NotContains(s.T(), buf.String(), `href="/b"`)
+
+	buf.Reset()
+	err = comp.Convert([]byte("{{ LINK url=\"https://umono.io\" }}\n\n{{ LINK url=\"vbscript:x\" }}"), &buf, WithStrict(true))
+	var componoErr *ComponoError
+	require.ErrorAs(s.T(), err, &componoErr)
+	require.Len(s.T(), componoErr.Diagnostics, 1)
+	assert.Equal(s.T(), errwrap.CodeUnsafeURL, componoErr.Diagnostics[0].Code)
+	assert.True(s.T(), componoErr.Diagnostics[0].Block)
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("{{ LINK }}\n\n~ LINK url=\"javascript:x\"\n{{ url }}"), &buf, WithStrict(true)))
+	assert.Equal(s.T(), "<p>javascript:x</p>", buf.String())
+}
+
+func (s *componoTestSuite) TestLinkResolver() {
+	comp := New()
+	require.Nil(s.T(), comp.RegisterGlobalComponent("NAV", []byte("{{ LINK text=\"Docs\" url=\"page:docs\" new-tab=true }}")))
+	require.Nil(s.T(), comp.RegisterGlobalComponent("UNUSED", []byte("[Unused](page:unused)")))
+	resolved := []string{}
+	comp.SetLinkResolver(links.ResolverFunc(func(url string) links.Resolution {
+		resolved = append(resolved, url)
+		if page, ok := strings.CutPrefix(url, "page:"); ok {
+			return links.Resolution{URL: "/" + page, Broken: page == "missing"}
+		}
+		if strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "https://umono.io") {
+			return links.Resolution{Rel: "nofollow", Target: "_blank"}
+		}
+		return links.Resolution{}
+	}))
+	source := []byte("[About](page:about-us) [Ext](https://example.com) [Home](https://umono.io) [JS](javascript:x)\n\n{{ NAV }}")
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert(source, &buf))
+	assert.Equal(s.T(), `<p><a href="/about-us">About</a> <a href="https://example.com" target="_blank" rel="nofollow noopener noreferrer">Ext</a> <a href="https://umono.io">Home</a> <a href="#">JS</a></p><a href="/docs" target="_blank" rel="noopener noreferrer">Docs</a>`, buf.String())
+	assert.Equal(s.T(), []string{"page:about-us", "https://example.com", "https://umono.io", "javascript:x", "page:docs"}, resolved)
+
+	found, err := comp.Links(source)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), found, 5)
+	assert.Equal(s.T(), links.Link{URL: "page:about-us", Href: "/about-us", Range: ast.Range{
+		Start: ast.Position{Offset: 8, Line: 1, Column: 9},
+		End:   ast.Position{Offset: 21, Line: 1, Column: 22},
+	}}, *found[0])
+	assert.Equal(s.T(), "nofollow", found[1].Rel)
+	assert.Equal(s.T(), "_blank", found[1].Target)
+	assert.True(s.T(), found[3].Unsafe)
+	assert.Equal(s.T(), "/docs", found[4].Href)
+	assert.Equal(s.T(), "NAV", found[4].Range.Start.Source)
+
+	buf.Reset()
+	err = comp.Convert([]byte("[Gone](page:missing)\n\n{{ LINK url=\"page:missing\" }}"), &buf, WithStrict(true))
+	var componoErr *ComponoError
+	require.ErrorAs(s.T(), err, &componoErr)
+	require.Len(s.T(), componoErr.Diagnostics, 2)
+	assert.Equal(s.T(), errwrap.CodeBrokenLink, componoErr.Diagnostics[0].Code)
+	assert.Equal(s.T(), "The link target page:missing does not exist.", componoErr.Diagnostics[0].Message)
+	assert.True(s.T(), componoErr.Diagnostics[1].Block)
+
+	found, err = comp.Links([]byte("[Gone](page:missing)"))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), found, 1)
+	assert.True(s.T(), found[0].Broken)
+
+	buf.Reset()
+	require.Nil(s.T(), comp.Convert([]byte("[About](page:about-us)"), &buf, WithLinkResolver(nil), WithURLPolicy(nil)))
+	assert.Equal(s.T(), `<p><a href="page:about-us">About</a></p>`, buf.String())
+}
+
+func (s *componoTestSuite) TestFork() {
+	base := New()
+	require.Nil(s.T(), base.RegisterGlobalComponent("HEADER", []byte("Base header")))
+	require.Nil(s.T(), base.RegisterGlobalComponent("FOOTER", []byte("Base footer")))
+	require.Nil(s.T(), base.RegisterGlobalComponent("PAGE", []byte("{{ HEADER }}\n\n{{ FOOTER }}")))
+
+	site := base.Fork("site")
+	require.Nil(s.T(), site.RegisterGlobalComponent("HEADER", []byte("Site header")))
+
+	section := site.Fork("section")
+	require.Nil(s.T(), section.RegisterGlobalComponent("FOOTER", []byte("Section footer {{ MISSING }}")))
+	require.Nil(s.T(), section.RegisterGlobalComponent("LINK", []byte("Section link")))
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), base.Convert([]byte("{{ PAGE }}"), &buf))
+	assert.Equal(s.T(), "<p>Base header</p><p>Base footer</p>", buf.String())
+
+	buf.Reset()
+	require.Nil(s.T(), site.Convert([]byte("{{ PAGE }}"), &buf))
+	assert.Equal(s.T(), "<p>Site header</p><p>Base footer</p>", buf.String())
+
+	buf.Reset()
+	require.Nil(s.T(), section.Convert([]byte("{{ PAGE }}\n\n{{ LINK }}"), &buf))
+	assert.Contains(s.T(), buf.String(), "<p>Site header</p><p>Section footer")
+	assert.Contains(s.T(), buf.String(), "<p>Section link</p>")
+
+	buf.Reset()
+	require.Nil(s.T(), section.Convert([]byte("{{ HEADER }}\n\n~ HEADER\nLocal header"), &buf))
+	assert.Equal(s.T(), "<p>Local header</p>", buf.String())
+
+	err := section.UnregisterGlobalComponent("HEADER")
+	var componoErr *ComponoError
+	require.True(s.T(), errors.As(err, &componoErr))
+	assert.Equal(s.T(), ErrGlobalNotExist, componoErr.Code)
+
+	diagnostics, err := section.Lint([]byte("{{ PAGE }}"))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), diagnostics, 1)
+	assert.Equal(s.T(), "section", diagnostics[0].Layer)
+	assert.Equal(s.T(), "FOOTER", diagnostics[0].Range.Start.Source)
+	assert.True(s.T(), strings.HasPrefix(diagnostics[0].Error(), "section:FOOTER:1:"))
+
+	infos, err := section.Components(nil)
+	require.Nil(s.T(), err)
+	layers := map[string]string{}
+	for _, info := range infos {
+		if info.Kind == KindGlobal {
+			layers[info.Name] = info.Layer
+		}
+	}
+	assert.Equal(s.T(), map[string]string{
+		"FOOTER": "section",
+		"HEADER": "site",
+		"LINK":   "section",
+		"PAGE":   "",
+	}, layers)
+
+	deps, err := site.Dependencies([]byte("{{ PAGE }}"))
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"FOOTER", "HEADER", "PAGE"}, deps)
+}
+
+func (s *componoTestSuite) TestForksInParallel() {
+	base := New()
+	require.Nil(s.T(), base.RegisterGlobalComponent("HEADER", []byte("## Base")))
+
+	site := base.Fork("site")
+	r := html.NewRenderer(logger.NewLogger())
+	r.Override("h2-content", html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		return `<h2 class="site">` + ctx.RenderChildren(node.Children()) + "</h2>"
+	}))
+	site.SetRenderer(r)
+
+	var wg sync.WaitGroup
+	for layer, want := range map[Compono]string{base: "<h2>Base</h2>", site: `<h2 class="site">Base</h2>`} {
+		wg.Add(1)
+		go func(layer Compono, want string) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				var buf bytes.Buffer
+				assert.Nil(s.T(), layer.Convert([]byte("{{ HEADER }}"), &buf))
+				assert.Equal(s.T(), want, buf.String())
+			}
+		}(layer, want)
+	}
+	wg.Wait()
+}
+
+func (s *componoTestSuite) TestRendererOverride() {
+	r := html.NewRenderer(logger.NewLogger())
+	r.Override("h2-content", html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		return `<h2 class="title">` + ctx.RenderChildren(node.Children()) + "</h2>"
+	}))
+	r.Override("strong-content", html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		return "<mark>" + ctx.RenderDefault() + "</mark>"
+	}))
+
+	comp := New()
+	comp.SetRenderer(r)
+	require.Nil(s.T(), comp.RegisterGlobalComponent("CARD", []byte("title = \"\"\n## {{ title }}")))
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("## Hello **world**\n\n{{ CARD title=\"Card\" }}"), &buf))
+	assert.Equal(s.T(), `<h2 class="title">Hello <mark><strong>world</strong></mark></h2><h2 class="title">Card</h2>`, buf.String())
+}
+
+func (s *componoTestSuite) TestRendererOverrideBuiltin() {
+	r := html.NewRenderer(logger.NewLogger())
+	r.OverrideBuiltin("LINK", html.NodeRendererFunc(func(ctx *html.Context, call ast.Node) string {
+		url, _ := html.ArgValue(call, "url")
+		if url != "/old" {
+			return ctx.RenderDefault()
+		}
+		return `<span class="moved">` + ctx.RenderDefault() + "</span>"
+	}))
+
+	comp := New()
+	comp.SetRenderer(r)
+
+	var buf bytes.Buffer
+	require.Nil(s.T(), comp.Convert([]byte("{{ LINK text=\"Old\" url=\"/old\" }}\n\n{{ LINK text=\"Docs\" url=\"/docs\" }}"), &buf))
+	assert.Equal(s.T(), `<span class="moved"><a href="/old">Old</a></span><a href="/docs">Docs</a>`, buf.String())
+}
+
 func TestComponoTestSuite(t *testing.T) {
 	suite.Run(t, new(componoTestSuite))
 }
@@ -0,0 +1,152 @@
+package compono
+
+import (
+	"sort"
+
+	"github.com/umono-cms/compono/ast"
+)
+
+// Dependencies returns the names of the global components source uses,
+// directly or through other components, sorted. Builtins are not included.
+func (c *compono) Dependencies(source []byte) ([]string, error) {
+	if len(source) == 0 {
+		return []string{}, nil
+	}
+
+	root, err := c.parseRoot(source)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := map[string]bool{}
+	for _, child := range root.Children() {
+		if ast.IsRuleNameOneOf(child, []string{"global-comp-def-wrapper", "builtin-comp-wrapper"}) {
+			continue
+		}
+		collectDependencies(root, root, child, deps)
+	}
+
+	return sortedNames(deps), nil
+}
+
+// Dependents returns the names of the registered or stored global
+// components that use the global component name, directly or through other
+// components, sorted.
+func (c *compono) Dependents(name string) ([]string, error) {
+	graph, err := c.dependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := map[string]bool{}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for globalName, uses := range graph {
+			if dependents[globalName] || !uses[current] {
+				continue
+			}
+			dependents[globalName] = true
+			queue = append(queue, globalName)
+		}
+	}
+	delete(dependents, name)
+
+	return sortedNames(dependents), nil
+}
+
+// dependencyGraph maps each global component to the globals it uses
+// directly.
+func (c *compono) dependencyGraph() (map[string]map[string]bool, error) {
+	root, err := c.allGlobalsRoot()
+	if err != nil {
+		return nil, err
+	}
+	gw := ast.FindNodeByRuleName(root.Children(), "global-comp-def-wrapper")
+
+	graph := map[string]map[string]bool{}
+	for _, globalCompDef := range gw.Children() {
+		uses := map[string]bool{}
+		for _, name := range compRefNames(globalCompDef) {
+			if ast.FindLocalCompDef(globalCompDef, name) != nil {
+				continue
+			}
+			if usedCompDef := ast.ResolveGlobalCompDef(root, globalCompDef, name); usedCompDef != nil {
+				uses[globalCompName(usedCompDef)] = true
+			}
+		}
+		graph[globalCompName(globalCompDef)] = uses
+	}
+
+	return graph, nil
+}
+
+// allGlobalsRoot returns a root holding copies of every registered and
+// stored global component.
+func (c *compono) allGlobalsRoot() (ast.Node, error) {
+	return c.globalsRoot(c.cloneGlobalComponents())
+}
+
+// globalsRoot returns a root holding globalCompDefs and copies of the
+// stored global components they do not shadow.
+func (c *compono) globalsRoot(globalCompDefs []ast.Node) (ast.Node, error) {
+	root := ast.DefaultRootNode()
+	gw := c.newGlobalWrapper(globalCompDefs)
+	gw.SetParent(root)
+	root.SetChildren([]ast.Node{gw})
+
+	for _, layer := range c.layers() {
+		if layer.Store() == nil {
+			continue
+		}
+		names, err := layer.Store().List()
+		if err != nil {
+			return nil, NewComponoError(ErrStore, "cannot list global components: "+err.Error())
+		}
+		for _, name := range names {
+			if ast.FindGlobalCompDef(root, name) != nil {
+				continue
+			}
+			node, err := layer.lookupStoredGlobal(name)
+			if err != nil {
+				return nil, err
+			}
+			if node == nil {
+				continue
+			}
+			node.SetParent(gw)
+			gw.SetChildren(append(gw.Children(), node))
+		}
+	}
+
+	return root, nil
+}
+
+// collectDependencies adds to deps the global components node uses, with
+// names resolved in scope, the root or a global component definition.
+func collectDependencies(root ast.Node, scope ast.Node, node ast.Node, deps map[string]bool) {
+	for _, name := range compRefNames(node) {
+		if ast.FindLocalCompDef(scope, name) != nil {
+			continue
+		}
+
+		globalCompDef := ast.ResolveGlobalCompDef(root, scope, name)
+		if globalCompDef == nil || deps[globalCompName(globalCompDef)] {
+			continue
+		}
+
+		deps[globalCompName(globalCompDef)] = true
+		collectDependencies(root, globalCompDef, globalCompDef, deps)
+	}
+}
+
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
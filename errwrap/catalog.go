@@ -0,0 +1,312 @@
+package errwrap
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MessageID identifies an error title or message in a Catalog.
+type MessageID string
+
+const (
+	TitleInfiniteCompCall  MessageID = "title.infinite-comp-call"
+	TitleUnknownComp       MessageID = "title.unknown-comp"
+	TitleInvalidCompUsage  MessageID = "title.invalid-comp-usage"
+	TitleUnknownParam      MessageID = "title.unknown-param"
+	TitleWrongArgType      MessageID = "title.wrong-arg-type"
+	TitleInvalidParamUsage MessageID = "title.invalid-param-usage"
+	TitleNotCompParam      MessageID = "title.not-comp-param"
+	TitleUnusedComp        MessageID = "title.unused-comp"
+	TitleUnusedParam       MessageID = "title.unused-param"
+	TitleDuplicateComp     MessageID = "title.duplicate-comp"
+	TitleShadowedComp      MessageID = "title.shadowed-comp"
+	TitleUnsafeURL         MessageID = "title.unsafe-url"
+	TitleBrokenLink        MessageID = "title.broken-link"
+
+	MsgInfiniteCompCall   MessageID = "msg.infinite-comp-call"
+	MsgUnknownComp        MessageID = "msg.unknown-comp"
+	MsgUnknownComps       MessageID = "msg.unknown-comps"
+	MsgBlockCompInInline  MessageID = "msg.block-comp-in-inline"
+	MsgUndefinedParam     MessageID = "msg.undefined-param"
+	MsgUndefinedParams    MessageID = "msg.undefined-params"
+	MsgSomeUndefinedParam MessageID = "msg.some-undefined-param"
+	MsgWrongArgType       MessageID = "msg.wrong-arg-type"
+	MsgWrongArgTypes      MessageID = "msg.wrong-arg-types"
+	MsgSomeWrongArgType   MessageID = "msg.some-wrong-arg-type"
+	MsgParamRefInRoot     MessageID = "msg.param-ref-in-root"
+	MsgNotCompParam       MessageID = "msg.not-comp-param"
+	MsgDidYouMean         MessageID = "msg.did-you-mean"
+	MsgUnusedComp         MessageID = "msg.unused-comp"
+	MsgUnusedParam        MessageID = "msg.unused-param"
+	MsgDuplicateComp      MessageID = "msg.duplicate-comp"
+	MsgShadowsGlobalComp  MessageID = "msg.shadows-global-comp"
+	MsgShadowsBuiltinComp MessageID = "msg.shadows-builtin-comp"
+	MsgUnknownLayout      MessageID = "msg.unknown-layout"
+	MsgInfiniteLayout     MessageID = "msg.infinite-layout"
+	MsgContentNoLayout    MessageID = "msg.content-no-layout"
+	MsgDuplicateLayout    MessageID = "msg.duplicate-layout"
+	MsgMisplacedLayout    MessageID = "msg.misplaced-layout"
+	MsgRepeatedContent    MessageID = "msg.repeated-content"
+	MsgUnsafeURL          MessageID = "msg.unsafe-url"
+	MsgBrokenLink         MessageID = "msg.broken-link"
+)
+
+const DefaultLocale = "en"
+
+// Catalog provides the templates of error titles and messages. Templates
+// refer to their arguments by position, like {0}, so translations can
+// reorder them.
+type Catalog interface {
+	Lookup(locale string, id MessageID) (string, bool)
+}
+
+// MapCatalog is a Catalog keyed by locale and message ID.
+type MapCatalog map[string]map[MessageID]string
+
+func (mc MapCatalog) Lookup(locale string, id MessageID) (string, bool) {
+	templates, ok := mc[locale]
+	if !ok {
+		return "", false
+	}
+	template, ok := templates[id]
+	return template, ok
+}
+
+// DefaultCatalog returns a catalog with English, Turkish and German
+// templates.
+func DefaultCatalog() Catalog {
+	return MapCatalog{
+		"en": english,
+		"tr": turkish,
+		"de": german,
+	}
+}
+
+var english = map[MessageID]string{
+	TitleInfiniteCompCall:  "Infinite component call",
+	TitleUnknownComp:       "Unknown component",
+	TitleInvalidCompUsage:  "Invalid component usage",
+	TitleUnknownParam:      "Unknown parameter",
+	TitleWrongArgType:      "Wrong argument type",
+	TitleInvalidParamUsage: "Invalid parameter usage",
+	TitleNotCompParam:      "Not component parameter",
+	TitleUnusedComp:        "Unused component",
+	TitleUnusedParam:       "Unused parameter",
+	TitleDuplicateComp:     "Duplicate component",
+	TitleShadowedComp:      "Shadowed component",
+	TitleUnsafeURL:         "Unsafe URL",
+	TitleBrokenLink:        "Broken link",
+
+	MsgInfiniteCompCall:   "The call to component {0} creates an infinite loop and was skipped.",
+	MsgUnknownComp:        "The component {0} is not defined or not registered.",
+	MsgUnknownComps:       "The components {0} are not defined or not registered.",
+	MsgBlockCompInInline:  "The component {0} is a block component and cannot be used inline.",
+	MsgUndefinedParam:     "The parameter {0} is not defined for this component.",
+	MsgUndefinedParams:    "The parameters {0} are not defined for this component.",
+	MsgSomeUndefinedParam: "One or more parameters are not defined for this component.",
+	MsgWrongArgType:       "The parameter {0} has the wrong type.",
+	MsgWrongArgTypes:      "The parameters {0} have the wrong type.",
+	MsgSomeWrongArgType:   "One or more arguments have the wrong type for this component.",
+	MsgParamRefInRoot:     "Parameters cannot be used in the root context.",
+	MsgNotCompParam:       "The parameter {0} is not component parameter",
+	MsgDidYouMean:         "Did you mean {0}?",
+	MsgUnusedComp:         "The component {0} is defined but never used.",
+	MsgUnusedParam:        "The parameter {0} is never used in component {1}.",
+	MsgDuplicateComp:      "The component {0} is defined more than once. Only the first definition is used.",
+	MsgShadowsGlobalComp:  "The local component {0} shadows the global component with the same name.",
+	MsgShadowsBuiltinComp: "The local component {0} shadows the built-in component with the same name.",
+	MsgUnknownLayout:      "The layout {0} is not a global component.",
+	MsgInfiniteLayout:     "The layout {0} leads back to this component through its layouts.",
+	MsgContentNoLayout:    "{0} only renders inside a layout and renders nothing here.",
+	MsgDuplicateLayout:    "Only the first layout declaration is used, so the layout {0} is ignored.",
+	MsgMisplacedLayout:    "The layout {0} must be declared on the first line and is ignored.",
+	MsgRepeatedContent:    "{0} can be used only once in a layout and renders nothing here.",
+	MsgUnsafeURL:          "The URL {0} is not allowed by the URL policy.",
+	MsgBrokenLink:         "The link target {0} does not exist.",
+}
+
+var turkish = map[MessageID]string{
+	TitleInfiniteCompCall:  "Sonsuz bileşen çağrısı",
+	TitleUnknownComp:       "Bilinmeyen bileşen",
+	TitleInvalidCompUsage:  "Geçersiz bileşen kullanımı",
+	TitleUnknownParam:      "Bilinmeyen parametre",
+	TitleWrongArgType:      "Yanlış argüman türü",
+	TitleInvalidParamUsage: "Geçersiz parametre kullanımı",
+	TitleNotCompParam:      "Bileşen parametresi değil",
+	TitleUnusedComp:        "Kullanılmayan bileşen",
+	TitleUnusedParam:       "Kullanılmayan parametre",
+	TitleDuplicateComp:     "Yinelenen bileşen",
+	TitleShadowedComp:      "Gölgelenen bileşen",
+	TitleUnsafeURL:         "Güvenli olmayan URL",
+	TitleBrokenLink:        "Kırık bağlantı",
+
+	MsgInfiniteCompCall:   "{0} bileşeni çağrısı sonsuz döngü oluşturduğu için atlandı.",
+	MsgUnknownComp:        "{0} bileşeni tanımlı veya kayıtlı değil.",
+	MsgUnknownComps:       "{0} bileşenleri tanımlı veya kayıtlı değil.",
+	MsgBlockCompInInline:  "{0} bir blok bileşenidir ve satır içinde kullanılamaz.",
+	MsgUndefinedParam:     "{0} parametresi bu bileşen için tanımlı değil.",
+	MsgUndefinedParams:    "{0} parametreleri bu bileşen için tanımlı değil.",
+	MsgSomeUndefinedParam: "Bir veya daha fazla parametre bu bileşen için tanımlı değil.",
+	MsgWrongArgType:       "{0} parametresinin türü yanlış.",
+	MsgWrongArgTypes:      "{0} parametrelerinin türü yanlış.",
+	MsgSomeWrongArgType:   "Bir veya daha fazla argümanın türü bu bileşen için yanlış.",
+	MsgParamRefInRoot:     "Parametreler kök içerikte kullanılamaz.",
+	MsgNotCompParam:       "{0} parametresi bir bileşen parametresi değil.",
+	MsgDidYouMean:         "Bunu mu demek istediniz: {0}?",
+	MsgUnusedComp:         "{0} bileşeni tanımlı ama hiç kullanılmıyor.",
+	MsgUnusedParam:        "{0} parametresi {1} bileşeninde hiç kullanılmıyor.",
+	MsgDuplicateComp:      "{0} bileşeni birden fazla kez tanımlanmış. Yalnızca ilk tanım kullanılır.",
+	MsgShadowsGlobalComp:  "{0} yerel bileşeni aynı adlı global bileşeni gölgeliyor.",
+	MsgShadowsBuiltinComp: "{0} yerel bileşeni aynı adlı yerleşik bileşeni gölgeliyor.",
+	MsgUnknownLayout:      "{0} düzeni bir global bileşen değil.",
+	MsgInfiniteLayout:     "{0} düzeni, düzenleri üzerinden bu bileşene geri dönüyor.",
+	MsgContentNoLayout:    "{0} yalnızca bir düzen içinde görüntülenir, burada hiçbir şey görüntülemez.",
+	MsgDuplicateLayout:    "Yalnızca ilk düzen tanımı kullanıldığı için {0} düzeni yok sayıldı.",
+	MsgMisplacedLayout:    "{0} düzeni ilk satırda tanımlanmalıdır ve yok sayıldı.",
+	MsgRepeatedContent:    "{0} bir düzende yalnızca bir kez kullanılabilir, burada hiçbir şey görüntülemez.",
+	MsgUnsafeURL:          "{0} adresine URL politikası izin vermiyor.",
+	MsgBrokenLink:         "{0} bağlantı hedefi mevcut değil.",
+}
+
+var german = map[MessageID]string{
+	TitleInfiniteCompCall:  "Endloser Komponentenaufruf",
+	TitleUnknownComp:       "Unbekannte Komponente",
+	TitleInvalidCompUsage:  "Ungültige Verwendung einer Komponente",
+	TitleUnknownParam:      "Unbekannter Parameter",
+	TitleWrongArgType:      "Falscher Argumenttyp",
+	TitleInvalidParamUsage: "Ungültige Verwendung eines Parameters",
+	TitleNotCompParam:      "Kein Komponentenparameter",
+	TitleUnusedComp:        "Unbenutzte Komponente",
+	TitleUnusedParam:       "Unbenutzter Parameter",
+	TitleDuplicateComp:     "Doppelte Komponente",
+	TitleShadowedComp:      "Verdeckte Komponente",
+	TitleUnsafeURL:         "Unsichere URL",
+	TitleBrokenLink:        "Defekter Link",
+
+	MsgInfiniteCompCall:   "Der Aufruf der Komponente {0} erzeugt eine Endlosschleife und wurde übersprungen.",
+	MsgUnknownComp:        "Die Komponente {0} ist nicht definiert oder nicht registriert.",
+	MsgUnknownComps:       "Die Komponenten {0} sind nicht definiert oder nicht registriert.",
+	MsgBlockCompInInline:  "Die Komponente {0} ist eine Blockkomponente und kann nicht inline verwendet werden.",
+	MsgUndefinedParam:     "Der Parameter {0} ist für diese Komponente nicht definiert.",
+	MsgUndefinedParams:    "Die Parameter {0} sind für diese Komponente nicht definiert.",
+	MsgSomeUndefinedParam: "Ein oder mehrere Parameter sind für diese Komponente nicht definiert.",
+	MsgWrongArgType:       "Der Parameter {0} hat den falschen Typ.",
+	MsgWrongArgTypes:      "Die Parameter {0} haben den falschen Typ.",
+	MsgSomeWrongArgType:   "Ein oder mehrere Argumente haben den falschen Typ für diese Komponente.",
+	MsgParamRefInRoot:     "Parameter können im Wurzelkontext nicht verwendet werden.",
+	MsgNotCompParam:       "Der Parameter {0} ist kein Komponentenparameter.",
+	MsgDidYouMean:         "Meinten Sie {0}?",
+	MsgUnusedComp:         "Die Komponente {0} ist definiert, wird aber nie verwendet.",
+	MsgUnusedParam:        "Der Parameter {0} wird in der Komponente {1} nie verwendet.",
+	MsgDuplicateComp:      "Die Komponente {0} ist mehrfach definiert. Nur die erste Definition wird verwendet.",
+	MsgShadowsGlobalComp:  "Die lokale Komponente {0} verdeckt die globale Komponente mit demselben Namen.",
+	MsgShadowsBuiltinComp: "Die lokale Komponente {0} verdeckt die eingebaute Komponente mit demselben Namen.",
+	MsgUnknownLayout:      "Das Layout {0} ist keine globale Komponente.",
+	MsgInfiniteLayout:     "Das Layout {0} führt über seine Layouts zurück zu dieser Komponente.",
+	MsgContentNoLayout:    "{0} wird nur in einem Layout dargestellt und zeigt hier nichts an.",
+	MsgDuplicateLayout:    "Nur die erste Layout-Deklaration wird verwendet, daher wird das Layout {0} ignoriert.",
+	MsgMisplacedLayout:    "Das Layout {0} muss in der ersten Zeile deklariert werden und wird ignoriert.",
+	MsgRepeatedContent:    "{0} kann in einem Layout nur einmal verwendet werden und zeigt hier nichts an.",
+	MsgUnsafeURL:          "Die URL {0} ist laut URL-Richtlinie nicht erlaubt.",
+	MsgBrokenLink:         "Das Linkziel {0} existiert nicht.",
+}
+
+// Arg is a message argument: one or more component or parameter names, or
+// other values, such as URLs, written as text.
+type Arg struct {
+	Kind   PartKind
+	Values []string
+}
+
+func compArg(compNames ...string) Arg {
+	return Arg{Kind: ComponentPart, Values: compNames}
+}
+
+func paramArg(paramNames ...string) Arg {
+	return Arg{Kind: ParamPart, Values: paramNames}
+}
+
+func textArg(values ...string) Arg {
+	return Arg{Kind: TextPart, Values: values}
+}
+
+type localizable struct {
+	id          MessageID
+	args        []Arg
+	suggestions Arg
+}
+
+func localize(id MessageID, args ...Arg) localizable {
+	return localizable{id: id, args: args}
+}
+
+func (l localizable) withSuggestions(suggestions Arg) localizable {
+	l.suggestions = suggestions
+	return l
+}
+
+// lookupTemplate tries the locale, its base language ("de" for "de-AT"),
+// the default locale and finally the built-in English templates.
+func lookupTemplate(catalog Catalog, locale string, id MessageID) string {
+	locales := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		locales = append(locales, base)
+	}
+	locales = append(locales, DefaultLocale)
+
+	if catalog != nil {
+		for _, l := range locales {
+			if template, ok := catalog.Lookup(l, id); ok {
+				return template
+			}
+		}
+	}
+
+	if template, ok := english[id]; ok {
+		return template
+	}
+	return string(id)
+}
+
+// format fills the placeholders of template with args, keeping the names
+// apart from the surrounding text.
+func format(template string, args []Arg) Message {
+	msg := Message{}
+	rest := template
+
+	for {
+		start := strings.Index(rest, "{")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end == -1 {
+			break
+		}
+		end += start
+
+		idx, err := strconv.Atoi(rest[start+1 : end])
+		if err != nil || idx < 0 || idx >= len(args) {
+			msg = appendText(msg, rest[:end+1])
+			rest = rest[end+1:]
+			continue
+		}
+
+		msg = appendText(msg, rest[:start])
+		msg = append(msg, names(args[idx].Kind, args[idx].Values)...)
+		rest = rest[end+1:]
+	}
+
+	return appendText(msg, rest)
+}
+
+func appendText(msg Message, s string) Message {
+	if s == "" {
+		return msg
+	}
+	if len(msg) > 0 && msg[len(msg)-1].Kind == TextPart {
+		msg[len(msg)-1].Text += s
+		return msg
+	}
+	return append(msg, text(s))
+}
@@ -0,0 +1,223 @@
+package errwrap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+)
+
+type Code string
+
+const (
+	CodeInfiniteCompCall  Code = "infinite-comp-call"
+	CodeUnknownComp       Code = "unknown-comp"
+	CodeInvalidCompUsage  Code = "invalid-comp-usage"
+	CodeUnknownParam      Code = "unknown-param"
+	CodeWrongArgType      Code = "wrong-arg-type"
+	CodeInvalidParamUsage Code = "invalid-param-usage"
+	CodeNotCompParam      Code = "not-comp-param"
+	CodeUnsafeURL         Code = "unsafe-url"
+	CodeBrokenLink        Code = "broken-link"
+
+	CodeUnusedComp    Code = "unused-comp"
+	CodeUnusedParam   Code = "unused-param"
+	CodeDuplicateComp Code = "duplicate-comp"
+	CodeShadowedComp  Code = "shadowed-comp"
+)
+
+// Diagnostic describes a problem the error wrapper found and wrapped into a
+// block-error or inline-error node, or a lint warning. Layer names the forked
+// instance whose global component the problem is in, if any.
+type Diagnostic struct {
+	Severity    Severity  `json:"severity"`
+	Code        Code      `json:"code"`
+	Title       string    `json:"title"`
+	MessageID   MessageID `json:"messageId"`
+	Message     string    `json:"message"`
+	Parts       Message   `json:"parts"`
+	Suggestions []string  `json:"suggestions,omitempty"`
+	Block       bool      `json:"block"`
+	Range       ast.Range `json:"range"`
+	Layer       string    `json:"layer,omitempty"`
+}
+
+func (d *Diagnostic) Error() string {
+	msg := d.Title + ": " + d.Message
+	if d.Range.IsZero() {
+		return msg
+	}
+
+	pos := fmt.Sprintf("%d:%d", d.Range.Start.Line, d.Range.Start.Column)
+	if d.Range.Start.Source != "" {
+		pos = d.Range.Start.Source + ":" + pos
+	}
+	if d.Layer != "" {
+		pos = d.Layer + ":" + pos
+	}
+	return pos + ": " + msg
+}
+
+// Collect returns the diagnostics of the error nodes that would be rendered
+// for root, following component calls and layouts from the root content
+// into the definitions they use. Errors inside unused definitions are not reported.
+func Collect(root ast.Node) []*Diagnostic {
+	diagnostics := []*Diagnostic{}
+	walkRendered(root, func(node ast.Node) bool {
+		if ast.IsRuleNameOneOf(node, []string{"block-error", "inline-error"}) {
+			diagnostics = append(diagnostics, NewDiagnostic(node))
+			return false
+		}
+		return true
+	})
+
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	return diagnostics
+}
+
+// CollectWarnings returns the diagnostics of the lint warnings that Wrap
+// added to the end of root's content with WithWarnings, in order. Collect
+// leaves them out, since they do not fail strict conversions.
+func CollectWarnings(root ast.Node) []*Diagnostic {
+	rootContent := ast.FindNodeByRuleName(root.Children(), "root-content")
+	if rootContent == nil {
+		return nil
+	}
+
+	diagnostics := []*Diagnostic{}
+	for _, child := range rootContent.Children() {
+		if ast.IsRuleName(child, "block-warning") {
+			diagnostics = append(diagnostics, NewDiagnostic(child))
+		}
+	}
+
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	return diagnostics
+}
+
+func NewDiagnostic(errNode ast.Node) *Diagnostic {
+	msg := MessageOf(errNode)
+
+	suggestions := []string{}
+	for _, child := range errNode.Children() {
+		if ast.IsRuleName(child, "error-suggestion") {
+			suggestions = append(suggestions, string(child.Raw()))
+		}
+	}
+
+	severity := SeverityError
+	if ast.IsRuleName(errNode, "block-warning") {
+		severity = SeverityWarning
+	}
+
+	layer := ""
+	if errNode.Source() != nil {
+		layer = errNode.Source().Layer()
+	}
+
+	return &Diagnostic{
+		Severity:    severity,
+		Code:        Code(rawOfChild(errNode, "error-code")),
+		Title:       rawOfChild(errNode, "error-title"),
+		MessageID:   MessageID(rawOfChild(errNode, "error-message-id")),
+		Message:     msg.Plain(),
+		Parts:       msg,
+		Suggestions: suggestions,
+		Block:       !ast.IsRuleName(errNode, "inline-error"),
+		Range:       errNode.Range(),
+		Layer:       layer,
+	}
+}
+
+// Components returns the component names mentioned in the message.
+func (d *Diagnostic) Components() []string {
+	comps := []string{}
+	for _, part := range d.Parts {
+		if part.Kind == ComponentPart {
+			comps = append(comps, part.Text)
+		}
+	}
+	return comps
+}
+
+// walkRendered visits the nodes that would be rendered for root, following
+// component calls and layouts from the root content into the definitions
+// they use, each once. The children of a node are skipped when visit
+// returns false.
+func walkRendered(root ast.Node, visit func(ast.Node) bool) {
+	c := &collector{
+		root:    root,
+		visited: map[ast.Node]bool{},
+		visit:   visit,
+	}
+
+	rootContent := ast.FindNodeByRuleName(root.Children(), "root-content")
+	if rootContent != nil {
+		c.walk(rootContent)
+	}
+}
+
+type collector struct {
+	root    ast.Node
+	visited map[ast.Node]bool
+	visit   func(ast.Node) bool
+}
+
+func (c *collector) walk(node ast.Node) {
+	if !c.visit(node) {
+		return
+	}
+
+	if ast.IsRuleNameOneOf(node, []string{"block-comp-call", "inline-comp-call"}) {
+		c.follow(node, getCompCallNameStr(node))
+		for _, compArg := range getExplicitCompArgMap(node) {
+			c.follow(node, compArg)
+		}
+	}
+
+	if ast.IsRuleName(node, "layout-decl") {
+		c.followDef(ast.ResolveGlobalCompDef(c.root, node, ast.GetLayoutNameFromLayoutDecl(node)))
+	}
+
+	for _, child := range node.Children() {
+		c.walk(child)
+	}
+}
+
+func (c *collector) follow(scope ast.Node, name string) {
+	if name == "" {
+		return
+	}
+
+	c.followDef(findCompDef(c.root, scope, name))
+}
+
+func (c *collector) followDef(compDef ast.Node) {
+	if compDef == nil || c.visited[compDef] {
+		return
+	}
+	c.visited[compDef] = true
+
+	content := getCompDefContent(compDef)
+	if content == nil {
+		return
+	}
+
+	c.walk(content)
+
+	for _, defVal := range getCompDefCompParamDefaults(compDef) {
+		c.follow(content, defVal)
+	}
+}
+
+func rawOfChild(node ast.Node, ruleName string) string {
+	child := ast.FindNodeByRuleName(node.Children(), ruleName)
+	if child == nil {
+		return ""
+	}
+	return strings.TrimSpace(string(child.Raw()))
+}
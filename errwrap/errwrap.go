@@ -2,33 +2,110 @@ package errwrap
 
 import (
 	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/links"
 	"github.com/umono-cms/compono/rule"
+	"github.com/umono-cms/compono/urlpolicy"
 )
 
 type ErrorWrapper interface {
-	Wrap(ast.Node)
+	Wrap(ast.Node, ...WrapOption)
+	Lint(ast.Node, ...WrapOption) []*Diagnostic
+	Catalog() Catalog
+	SetCatalog(Catalog)
+}
+
+type WrapOption func(*wrapConfig)
+
+type wrapConfig struct {
+	locale       string
+	warnings     bool
+	urlPolicy    *urlpolicy.Policy
+	linkResolver links.Resolver
+}
+
+// WithLocale selects the locale of error titles and messages. Locales
+// missing from the catalog fall back to DefaultLocale.
+func WithLocale(locale string) WrapOption {
+	return func(cfg *wrapConfig) {
+		cfg.locale = locale
+	}
+}
+
+// WithWarnings makes Wrap append the lint warnings to the end of the root
+// content, so they are rendered after the document.
+func WithWarnings(warnings bool) WrapOption {
+	return func(cfg *wrapConfig) {
+		cfg.warnings = warnings
+	}
+}
+
+// WithURLPolicy checks the URLs of links and of the URL parameters of
+// builtin components against policy, which replaces the rejected ones or
+// has them wrapped as errors. Without it every URL is allowed.
+func WithURLPolicy(policy *urlpolicy.Policy) WrapOption {
+	return func(cfg *wrapConfig) {
+		cfg.urlPolicy = policy
+	}
+}
+
+// WithLinkResolver resolves the URLs of links and of the URL parameters of
+// builtin components with resolver before they are checked against the
+// URL policy. URLs it marks as broken are wrapped as errors.
+func WithLinkResolver(resolver links.Resolver) WrapOption {
+	return func(cfg *wrapConfig) {
+		cfg.linkResolver = resolver
+	}
 }
 
 func DefaultErrorWrapper() ErrorWrapper {
 	return &errorWrapper{
 		wrapRules: wrapRules(),
+		catalog:   DefaultCatalog(),
 	}
 }
 
 type errorWrapper struct {
-	root      ast.Node
 	wrapRules []wrapRule
+	catalog   Catalog
 }
 
-func (ew *errorWrapper) Wrap(root ast.Node) {
-	ew.root = root
+func (ew *errorWrapper) Catalog() Catalog {
+	return ew.catalog
+}
+
+func (ew *errorWrapper) SetCatalog(catalog Catalog) {
+	ew.catalog = catalog
+}
+
+func (ew *errorWrapper) Wrap(root ast.Node, opts ...WrapOption) {
+	cfg := configure(opts)
 
 	ctx := &wrapContext{
-		root:           root,
-		compCallChains: ew.getCompCallChains(root),
+		root:             root,
+		locale:           cfg.locale,
+		compCallChains:   ew.getCompCallChains(root),
+		layoutCycleDecls: getLayoutCycleDecls(root),
+		urlPolicy:        cfg.urlPolicy,
+		linkResolver:     cfg.linkResolver,
+	}
+
+	var warnings []warning
+	if cfg.warnings {
+		warnings = ew.lint(ctx)
 	}
 
+	resolveURLs(ctx, root)
 	ew.scanAndWrap(ctx, root)
+	replaceRejectedURLs(ctx, root)
+	ew.appendWarnings(root, warnings)
+}
+
+func configure(opts []WrapOption) *wrapConfig {
+	cfg := &wrapConfig{locale: DefaultLocale}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
 }
 
 func (ew *errorWrapper) scanAndWrap(ctx *wrapContext, node ast.Node) {
@@ -49,13 +126,31 @@ Outer:
 				continue Outer
 			}
 		}
-		ew.wrapWithErr(node, wr.title(ctx, node), wr.message(ctx, node), wr.block(ctx, node))
+		title := ew.translate(ctx, localize(wr.title)).Plain()
+		msg := wr.message(ctx, node)
+		ew.wrapWithErr(node, errInfo{
+			code:        wr.code,
+			title:       title,
+			msgID:       msg.id,
+			msg:         ew.translate(ctx, msg),
+			suggestions: msg.suggestions.Values,
+		}, wr.block(ctx, node))
 		return true
 	}
 
 	return false
 }
 
+func (ew *errorWrapper) translate(ctx *wrapContext, l localizable) Message {
+	msg := format(lookupTemplate(ew.catalog, ctx.locale, l.id), l.args)
+	if len(l.suggestions.Values) == 0 {
+		return msg
+	}
+
+	msg = appendText(msg, " ")
+	return append(msg, format(lookupTemplate(ew.catalog, ctx.locale, MsgDidYouMean), []Arg{l.suggestions})...)
+}
+
 func (ew *errorWrapper) getCompCallChains(root ast.Node) [][]ast.Node {
 	rootContent := ast.FindNodeByRuleName(root.Children(), "root-content")
 	compCalls := ast.FilterNodesInTree(rootContent, func(node ast.Node) bool {
@@ -105,12 +200,20 @@ func addLinkToChain(root ast.Node, chain *[]ast.Node, compCall ast.Node) {
 	}
 }
 
-func (ew *errorWrapper) wrapWithErr(self ast.Node, title, msg string, block bool) {
+type errInfo struct {
+	code        Code
+	title       string
+	msgID       MessageID
+	msg         Message
+	suggestions []string
+}
+
+func (ew *errorWrapper) wrapWithErr(self ast.Node, w errInfo, block bool) {
 	var errNode ast.Node
 	if block {
-		errNode = ew.createBlockError(self, title, msg)
+		errNode = ew.createBlockError(self, w)
 	} else {
-		errNode = ew.createInlineError(self, title, msg)
+		errNode = ew.createInlineError(self, w)
 	}
 
 	self.SetRule(errNode.Rule())
@@ -118,43 +221,51 @@ func (ew *errorWrapper) wrapWithErr(self ast.Node, title, msg string, block bool
 	self.SetRaw(errNode.Raw())
 }
 
-func (ew *errorWrapper) createBlockError(node ast.Node, title, msg string) ast.Node {
-	return ew.createError("block-error", node, title, msg)
+func (ew *errorWrapper) createBlockError(node ast.Node, w errInfo) ast.Node {
+	return ew.createError("block-error", node, w)
 }
 
-func (ew *errorWrapper) createInlineError(node ast.Node, title, msg string) ast.Node {
-	return ew.createError("inline-error", node, title, msg)
+func (ew *errorWrapper) createInlineError(node ast.Node, w errInfo) ast.Node {
+	return ew.createError("inline-error", node, w)
 }
 
-func (ew *errorWrapper) createError(errRuleName string, node ast.Node, title, msg string) ast.Node {
-	err := rule.NewDynamic(errRuleName)
-	errTitle := rule.NewDynamic("error-title")
-	errMsg := rule.NewDynamic("error-message")
-	self := rule.NewDynamic("self")
-
+func (ew *errorWrapper) createError(errRuleName string, node ast.Node, w errInfo) ast.Node {
 	errNode := ast.DefaultEmptyNode()
-	errNode.SetRule(err)
+	errNode.SetRule(rule.NewDynamic(errRuleName))
+	inheritPosition(errNode, node)
+
+	child := func(ruleName string, raw string) ast.Node {
+		childNode := ast.DefaultEmptyNode()
+		childNode.SetRule(rule.NewDynamic(ruleName))
+		childNode.SetParent(errNode)
+		childNode.SetRaw([]byte(raw))
+		inheritPosition(childNode, node)
+		return childNode
+	}
+
+	errMsgNode := child("error-message", w.msg.String())
+	errMsgNode.SetChildren(messagePartNodes(errMsgNode, w.msg))
 
-	errTitleNode := ast.DefaultEmptyNode()
-	errTitleNode.SetRule(errTitle)
-	errTitleNode.SetParent(errNode)
-	errTitleNode.SetRaw([]byte(title))
+	children := []ast.Node{
+		child("error-code", string(w.code)),
+		child("error-title", w.title),
+		child("error-message-id", string(w.msgID)),
+		errMsgNode,
+	}
 
-	errMsgNode := ast.DefaultEmptyNode()
-	errMsgNode.SetRule(errMsg)
-	errMsgNode.SetParent(errNode)
-	errMsgNode.SetRaw([]byte(msg))
+	for _, suggestion := range w.suggestions {
+		children = append(children, child("error-suggestion", suggestion))
+	}
 
-	selfNode := ast.DefaultEmptyNode()
-	selfNode.SetRule(self)
-	selfNode.SetParent(errNode)
+	selfNode := child("self", "")
 	selfNode.SetChildren(node.Children())
 
-	errNode.SetChildren([]ast.Node{
-		errTitleNode,
-		errMsgNode,
-		selfNode,
-	})
+	errNode.SetChildren(append(children, selfNode))
 
 	return errNode
 }
+
+func inheritPosition(dst ast.Node, src ast.Node) {
+	dst.SetSource(src.Source())
+	dst.SetOffsets(src.Offsets())
+}
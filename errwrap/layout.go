@@ -0,0 +1,205 @@
+package errwrap
+
+import (
+	"github.com/umono-cms/compono/ast"
+)
+
+func unknownLayout() wrapRule {
+	return wrapRule{
+		code: CodeUnknownComp,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleName("layout-decl"),
+			isUnknownLayout(),
+		},
+		title:   TitleUnknownComp,
+		message: unknownLayoutMsg,
+		block:   alwaysBlock,
+	}
+}
+
+func misplacedLayout() wrapRule {
+	return wrapRule{
+		code: CodeInvalidCompUsage,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleName("layout-decl"),
+			isMisplacedLayout(),
+		},
+		title:   TitleInvalidCompUsage,
+		message: misplacedLayoutMsg,
+		block:   alwaysBlock,
+	}
+}
+
+func infiniteLayout() wrapRule {
+	return wrapRule{
+		code: CodeInfiniteCompCall,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleName("layout-decl"),
+			isInLayoutCycle(),
+		},
+		title:   TitleInfiniteCompCall,
+		message: infiniteLayoutMsg,
+		block:   alwaysBlock,
+	}
+}
+
+func repeatedContent() wrapRule {
+	return wrapRule{
+		code: CodeInvalidCompUsage,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleName("block-comp-call"),
+			callsBuiltinContent(),
+			not(isOutsideGlobalCompDef()),
+			not(isFirstContentCall()),
+		},
+		title:   TitleInvalidCompUsage,
+		message: repeatedContentMsg,
+		block:   alwaysBlock,
+	}
+}
+
+func contentOutsideLayout() wrapRule {
+	return wrapRule{
+		code: CodeInvalidCompUsage,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleName("block-comp-call"),
+			callsBuiltinContent(),
+			isOutsideGlobalCompDef(),
+		},
+		title:   TitleInvalidCompUsage,
+		message: contentOutsideLayoutMsg,
+		block:   neverBlock,
+	}
+}
+
+func isUnknownLayout() func(*wrapContext, ast.Node) bool {
+	return func(ctx *wrapContext, node ast.Node) bool {
+		return ast.ResolveGlobalCompDef(ctx.root, node, ast.GetLayoutNameFromLayoutDecl(node)) == nil
+	}
+}
+
+// isMisplacedLayout reports whether the layout declaration is ignored,
+// because it does not come first in its content or another one does.
+func isMisplacedLayout() func(*wrapContext, ast.Node) bool {
+	return func(_ *wrapContext, node ast.Node) bool {
+		return ast.GetLayoutDecl(node.Parent()) != node
+	}
+}
+
+func isInLayoutCycle() func(*wrapContext, ast.Node) bool {
+	return func(ctx *wrapContext, node ast.Node) bool {
+		return ctx.layoutCycleDecls[node]
+	}
+}
+
+// callsBuiltinContent reports whether the call resolves to the built-in
+// CONTENT rather than to a component defined with the same name.
+func callsBuiltinContent() func(*wrapContext, ast.Node) bool {
+	return func(ctx *wrapContext, node ast.Node) bool {
+		if getCompCallNameStr(node) != "CONTENT" {
+			return false
+		}
+		compDef := findCompDef(ctx.root, node, "CONTENT")
+		return compDef != nil && ast.IsRuleName(compDef, "builtin-comp")
+	}
+}
+
+// isOutsideGlobalCompDef reports whether the node belongs to the page
+// itself. Only a global component can be a layout, so a CONTENT call of
+// the page always renders nothing.
+func isOutsideGlobalCompDef() func(*wrapContext, ast.Node) bool {
+	return func(_ *wrapContext, node ast.Node) bool {
+		return ast.FindNode(ast.GetAncestors(node), func(anc ast.Node) bool {
+			return ast.IsRuleName(anc, "global-comp-def")
+		}) == nil
+	}
+}
+
+// isFirstContentCall reports whether the CONTENT call is the first one of
+// its global component. A layout renders the content once, so the others
+// are reported and render nothing.
+func isFirstContentCall() func(*wrapContext, ast.Node) bool {
+	return func(ctx *wrapContext, node ast.Node) bool {
+		globalCompDef := ast.FindNode(ast.GetAncestors(node), func(anc ast.Node) bool {
+			return ast.IsRuleName(anc, "global-comp-def")
+		})
+		contentCalls := ast.FilterNodesInTree(globalCompDef, func(n ast.Node) bool {
+			return ast.IsRuleName(n, "block-comp-call") && callsBuiltinContent()(ctx, n)
+		})
+		return len(contentCalls) > 0 && contentCalls[0] == node
+	}
+}
+
+func unknownLayoutMsg(ctx *wrapContext, node ast.Node) localizable {
+	name := ast.GetLayoutNameFromLayoutDecl(node)
+	return localize(MsgUnknownLayout, compArg(name)).
+		withSuggestions(compArg(suggest(name, globalCompNames(ctx.root))...))
+}
+
+func misplacedLayoutMsg(_ *wrapContext, node ast.Node) localizable {
+	name := ast.GetLayoutNameFromLayoutDecl(node)
+	if ast.GetLayoutDecl(node.Parent()) != nil {
+		return localize(MsgDuplicateLayout, compArg(name))
+	}
+	return localize(MsgMisplacedLayout, compArg(name))
+}
+
+func repeatedContentMsg(_ *wrapContext, _ ast.Node) localizable {
+	return localize(MsgRepeatedContent, compArg("CONTENT"))
+}
+
+func contentOutsideLayoutMsg(_ *wrapContext, _ ast.Node) localizable {
+	return localize(MsgContentNoLayout, compArg("CONTENT"))
+}
+
+func infiniteLayoutMsg(_ *wrapContext, node ast.Node) localizable {
+	return localize(MsgInfiniteLayout, compArg(ast.GetLayoutNameFromLayoutDecl(node)))
+}
+
+// getLayoutCycleDecls returns the layout declarations of the global
+// components whose layout chain leads back to themselves. It runs before
+// any node is wrapped, so that every declaration of a cycle is reported.
+func getLayoutCycleDecls(root ast.Node) map[ast.Node]bool {
+	cycleDecls := map[ast.Node]bool{}
+
+	globalCompDefWrapper := ast.FindNodeByRuleName(root.Children(), "global-comp-def-wrapper")
+	if globalCompDefWrapper == nil {
+		return cycleDecls
+	}
+
+	for _, globalCompDef := range globalCompDefWrapper.Children() {
+		layoutDecl := ast.GetLayoutDecl(getCompDefContent(globalCompDef))
+		if layoutDecl == nil {
+			continue
+		}
+
+		visited := map[ast.Node]bool{}
+		current := layoutDecl
+		for current != nil {
+			layoutDef := ast.ResolveGlobalCompDef(root, current, ast.GetLayoutNameFromLayoutDecl(current))
+			if layoutDef == nil || visited[layoutDef] {
+				break
+			}
+			if layoutDef == globalCompDef {
+				cycleDecls[layoutDecl] = true
+				break
+			}
+			visited[layoutDef] = true
+			current = ast.GetLayoutDecl(getCompDefContent(layoutDef))
+		}
+	}
+
+	return cycleDecls
+}
+
+func globalCompNames(root ast.Node) []string {
+	names := []string{}
+	globalCompDefWrapper := ast.FindNodeByRuleName(root.Children(), "global-comp-def-wrapper")
+	if globalCompDefWrapper == nil {
+		return names
+	}
+	for _, globalCompDef := range globalCompDefWrapper.Children() {
+		names = append(names, rawOfChild(globalCompDef, "global-comp-name"))
+	}
+	return names
+}
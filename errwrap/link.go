@@ -0,0 +1,157 @@
+package errwrap
+
+import (
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/links"
+	"github.com/umono-cms/compono/rule"
+)
+
+// resolvedURL is the resolution of a URL node, with the URL as written.
+type resolvedURL struct {
+	written string
+	links.Resolution
+}
+
+func brokenLink() wrapRule {
+	return wrapRule{
+		code: CodeBrokenLink,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleName("link"),
+			hasBrokenURL(),
+		},
+		title:   TitleBrokenLink,
+		message: brokenLinkMsg,
+		block:   neverBlock,
+	}
+}
+
+func brokenURLArg() wrapRule {
+	return wrapRule{
+		code: CodeBrokenLink,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
+			hasBrokenURL(),
+		},
+		title:   TitleBrokenLink,
+		message: brokenLinkMsg,
+		block:   blockFromRuleName,
+	}
+}
+
+func hasBrokenURL() func(*wrapContext, ast.Node) bool {
+	return func(ctx *wrapContext, node ast.Node) bool {
+		return len(getBrokenURLs(ctx, node)) > 0
+	}
+}
+
+func brokenLinkMsg(ctx *wrapContext, node ast.Node) localizable {
+	return localize(MsgBrokenLink, textArg(getBrokenURLs(ctx, node)...))
+}
+
+// getBrokenURLs returns the written URLs of node that the link resolver
+// marked as broken.
+func getBrokenURLs(ctx *wrapContext, node ast.Node) []string {
+	broken := []string{}
+	for _, url := range getURLs(ctx, node) {
+		if resolved, ok := ctx.resolvedURLs[url]; ok && resolved.Broken {
+			broken = append(broken, resolved.written)
+		}
+	}
+	return broken
+}
+
+// resolveURLs resolves the URLs of the links and builtin component calls
+// that would be rendered for root with the link resolver, so that links of
+// unused components are not resolved. The URL nodes get the resolved URL as
+// their raw and the rel and target of the link as children.
+func resolveURLs(ctx *wrapContext, root ast.Node) {
+	ctx.resolvedURLs = map[ast.Node]resolvedURL{}
+	if ctx.linkResolver == nil {
+		return
+	}
+
+	walkRendered(root, func(node ast.Node) bool {
+		if !ast.IsRuleNameOneOf(node, []string{"link", "block-comp-call", "inline-comp-call"}) {
+			return true
+		}
+
+		for _, url := range getURLs(ctx, node) {
+			if _, ok := ctx.resolvedURLs[url]; ok {
+				continue
+			}
+
+			written := strings.TrimSpace(string(url.Raw()))
+			resolution := ctx.linkResolver.Resolve(written)
+			ctx.resolvedURLs[url] = resolvedURL{written: written, Resolution: resolution}
+
+			if resolution.URL != "" {
+				url.SetRaw([]byte(resolution.URL))
+			}
+			url.SetChildren(append(url.Children(), linkAttrNodes(url, resolution)...))
+		}
+		return true
+	})
+}
+
+func linkAttrNodes(parent ast.Node, resolution links.Resolution) []ast.Node {
+	nodes := []ast.Node{}
+	for _, attrs := range [][2]string{{"link-rel", resolution.Rel}, {"link-target", resolution.Target}} {
+		ruleName, value := attrs[0], attrs[1]
+		if value == "" {
+			continue
+		}
+		attr := ast.DefaultEmptyNode()
+		attr.SetRule(rule.NewDynamic(ruleName))
+		attr.SetParent(parent)
+		attr.SetRaw([]byte(value))
+		nodes = append(nodes, attr)
+	}
+	return nodes
+}
+
+// Links resolves the URLs of root like Wrap and returns the links that
+// would be rendered for root, following component calls and layouts from
+// the root content, in order. It does not wrap errors, so broken and
+// unsafe links are reported too. Only WithURLPolicy and WithLinkResolver
+// apply.
+func Links(root ast.Node, opts ...WrapOption) []*links.Link {
+	cfg := configure(opts)
+	ctx := &wrapContext{
+		root:         root,
+		urlPolicy:    cfg.urlPolicy,
+		linkResolver: cfg.linkResolver,
+	}
+	resolveURLs(ctx, root)
+
+	result := []*links.Link{}
+	walkRendered(root, func(node ast.Node) bool {
+		if !ast.IsRuleNameOneOf(node, []string{"link", "block-comp-call", "inline-comp-call"}) {
+			return true
+		}
+
+		for _, url := range getURLs(ctx, node) {
+			href := strings.TrimSpace(string(url.Raw()))
+			link := &links.Link{
+				URL:    href,
+				Href:   href,
+				Unsafe: !ctx.urlPolicy.Allows(href),
+				Range:  url.Range(),
+			}
+			if resolved, ok := ctx.resolvedURLs[url]; ok {
+				link.URL = resolved.written
+				link.Rel = resolved.Rel
+				link.Target = resolved.Target
+				link.Broken = resolved.Broken
+			}
+			if url.Source() != nil {
+				link.Layer = url.Source().Layer()
+			}
+			result = append(result, link)
+		}
+		return true
+	})
+
+	return result
+}
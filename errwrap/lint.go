@@ -0,0 +1,243 @@
+package errwrap
+
+import (
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type warning struct {
+	node ast.Node
+	info errInfo
+}
+
+// Lint reports problems in the definitions of root that do not stop it
+// from rendering: unused components and parameters, duplicate definitions
+// and locals shadowing a global or builtin component. The local
+// definitions of the page and the global components it renders, with
+// their own local definitions, are checked. It must run before Wrap.
+func (ew *errorWrapper) Lint(root ast.Node, opts ...WrapOption) []*Diagnostic {
+	cfg := configure(opts)
+	ctx := &wrapContext{root: root, locale: cfg.locale}
+
+	diagnostics := []*Diagnostic{}
+	for _, w := range ew.lint(ctx) {
+		diagnostics = append(diagnostics, NewDiagnostic(ew.createWarning(w)))
+	}
+	return diagnostics
+}
+
+func (ew *errorWrapper) lint(ctx *wrapContext) []warning {
+	root := ctx.root
+	warnings := ew.lintLocalCompDefs(ctx, root, ast.FindNodeByRuleName(root.Children(), "root-content"))
+
+	for _, globalCompDef := range renderedGlobalCompDefs(root) {
+		name := rawOfChild(globalCompDef, "global-comp-name")
+		// the local definitions of a global component may refer to its
+		// parameters too
+		warnings = append(warnings, ew.lintUnusedParams(ctx, globalCompDef, name, paramNamesReferencedIn(globalCompDef))...)
+		warnings = append(warnings, ew.lintLocalCompDefs(ctx, globalCompDef, getCompDefContent(globalCompDef))...)
+	}
+
+	return warnings
+}
+
+// lintLocalCompDefs checks the local definitions of owner, the root or a
+// global component, whose calls are looked for in content.
+func (ew *errorWrapper) lintLocalCompDefs(ctx *wrapContext, owner ast.Node, content ast.Node) []warning {
+	root := ctx.root
+	localCompDefWrapper := ast.FindNodeByRuleName(owner.Children(), "local-comp-def-wrapper")
+	if localCompDefWrapper == nil {
+		return nil
+	}
+
+	scope := []ast.Node{localCompDefWrapper}
+	if content != nil {
+		scope = append(scope, content)
+	}
+
+	warnings := []warning{}
+	defined := map[string]bool{}
+
+	for _, localCompDef := range localCompDefWrapper.Children() {
+		if !ast.IsRuleName(localCompDef, "local-comp-def") {
+			continue
+		}
+
+		head := ast.GetCompDefHeadFromCompDef(localCompDef)
+		if head == nil {
+			continue
+		}
+		nameNode := ast.FindNodeByRuleName(head.Children(), "local-comp-name")
+		if nameNode == nil {
+			continue
+		}
+		name := strings.TrimSpace(string(nameNode.Raw()))
+		if name == "" {
+			continue
+		}
+
+		if defined[name] {
+			warnings = append(warnings, ew.newWarning(ctx, nameNode, CodeDuplicateComp, TitleDuplicateComp, localize(MsgDuplicateComp, compArg(name))))
+			continue
+		}
+		defined[name] = true
+
+		if ast.FindGlobalCompDef(root, name) != nil {
+			warnings = append(warnings, ew.newWarning(ctx, nameNode, CodeShadowedComp, TitleShadowedComp, localize(MsgShadowsGlobalComp, compArg(name))))
+		} else if ast.FindBuiltinCompDef(root, name) != nil {
+			warnings = append(warnings, ew.newWarning(ctx, nameNode, CodeShadowedComp, TitleShadowedComp, localize(MsgShadowsBuiltinComp, compArg(name))))
+		}
+
+		if !isCompReferenced(scope, localCompDef, name) {
+			warnings = append(warnings, ew.newWarning(ctx, nameNode, CodeUnusedComp, TitleUnusedComp, localize(MsgUnusedComp, compArg(name))))
+		}
+
+		warnings = append(warnings, ew.lintUnusedParams(ctx, localCompDef, name, referencedParamNames(localCompDef))...)
+	}
+
+	return warnings
+}
+
+// lintUnusedParams reports the parameters of compDef that are not in used.
+func (ew *errorWrapper) lintUnusedParams(ctx *wrapContext, compDef ast.Node, name string, used map[string]bool) []warning {
+	warnings := []warning{}
+	for _, compParam := range ast.GetCompParamsFromCompDef(compDef) {
+		if !ast.IsRuleName(compParam, "comp-param") {
+			continue
+		}
+		paramName := ast.GetParamNameFromCompParam(compParam)
+		if paramName == "" || used[paramName] {
+			continue
+		}
+		warnings = append(warnings, ew.newWarning(ctx, compParam, CodeUnusedParam, TitleUnusedParam, localize(MsgUnusedParam, paramArg(paramName), compArg(name))))
+	}
+	return warnings
+}
+
+// renderedGlobalCompDefs returns the global components rendered for root,
+// in the order they are reached.
+func renderedGlobalCompDefs(root ast.Node) []ast.Node {
+	globalCompDefs := []ast.Node{}
+	walkRendered(root, func(node ast.Node) bool {
+		if ast.IsRuleName(node, "global-comp-def-content") && node.Parent() != nil {
+			globalCompDefs = append(globalCompDefs, node.Parent())
+		}
+		return true
+	})
+	return globalCompDefs
+}
+
+func (ew *errorWrapper) newWarning(ctx *wrapContext, node ast.Node, code Code, title MessageID, msg localizable) warning {
+	return warning{
+		node: node,
+		info: errInfo{
+			code:  code,
+			title: ew.translate(ctx, localize(title)).Plain(),
+			msgID: msg.id,
+			msg:   ew.translate(ctx, msg),
+		},
+	}
+}
+
+// createWarning builds a block-warning node. Unlike errors it does not keep
+// the node it points at, which is rendered, or not, as usual.
+func (ew *errorWrapper) createWarning(w warning) ast.Node {
+	warnNode := ew.createError("block-warning", w.node, w.info)
+	warnNode.SetChildren(ast.FilterNodes(warnNode.Children(), func(child ast.Node) bool {
+		return !ast.IsRuleName(child, "self")
+	}))
+	return warnNode
+}
+
+// appendWarnings adds the warnings to the end of the root content so that
+// renderers show them after the document.
+func (ew *errorWrapper) appendWarnings(root ast.Node, warnings []warning) {
+	rootContent := ast.FindNodeByRuleName(root.Children(), "root-content")
+	if rootContent == nil || len(warnings) == 0 {
+		return
+	}
+
+	children := rootContent.Children()
+	for _, w := range warnings {
+		warnNode := ew.createWarning(w)
+		warnNode.SetParent(rootContent)
+		children = append(children, warnNode)
+	}
+	rootContent.SetChildren(children)
+}
+
+// isCompReferenced reports whether name is called, passed as a component
+// argument or used as a component parameter default anywhere in scope
+// outside its own definition.
+func isCompReferenced(scope []ast.Node, compDef ast.Node, name string) bool {
+	for _, node := range scope {
+		if node == compDef {
+			continue
+		}
+
+		if ast.IsRuleNameOneOf(node, []string{"block-comp-call", "inline-comp-call"}) {
+			if getCompCallNameStr(node) == name {
+				return true
+			}
+		}
+
+		if ast.IsRuleNameOneOf(node, []string{"block-comp-call", "inline-comp-call", "param-ref"}) {
+			for _, compArg := range getExplicitCompArgMap(node) {
+				if compArg == name {
+					return true
+				}
+			}
+		}
+
+		if ast.IsRuleNameOneOf(node, []string{"local-comp-def", "global-comp-def"}) {
+			for _, defVal := range getCompDefCompParamDefaults(node) {
+				if defVal == name {
+					return true
+				}
+			}
+		}
+
+		if isCompReferenced(node.Children(), compDef, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// referencedParamNames returns the parameter names compDef's content refers
+// to, directly or by forwarding them as arguments.
+func referencedParamNames(compDef ast.Node) map[string]bool {
+	content := getCompDefContent(compDef)
+	if content == nil {
+		return map[string]bool{}
+	}
+	return paramNamesReferencedIn(content)
+}
+
+// paramNamesReferencedIn returns the parameter names referred to in the
+// tree of node, directly or by forwarding them as arguments.
+func paramNamesReferencedIn(node ast.Node) map[string]bool {
+	used := map[string]bool{}
+
+	refs := ast.FilterNodesInTree(node, func(n ast.Node) bool {
+		return ast.IsRuleNameOneOf(n, []string{"param-ref", "block-comp-call", "inline-comp-call"})
+	})
+	for _, ref := range refs {
+		if ast.IsRuleName(ref, "param-ref") {
+			used[getParamRefNameStr(ref)] = true
+		}
+		for _, paramArg := range getExplicitParamArgMap(ref) {
+			used[paramArg] = true
+		}
+	}
+
+	return used
+}
@@ -0,0 +1,110 @@
+package errwrap
+
+import (
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/rule"
+)
+
+type PartKind string
+
+const (
+	TextPart      PartKind = "text"
+	ComponentPart PartKind = "component"
+	ParamPart     PartKind = "param"
+)
+
+// MessagePart is a piece of an error message. Component and parameter names
+// are kept apart from the surrounding text so renderers can style them.
+type MessagePart struct {
+	Kind PartKind `json:"kind"`
+	Text string   `json:"text"`
+}
+
+type Message []MessagePart
+
+// String returns the message with names in **bold** Markdown markup.
+func (m Message) String() string {
+	var b strings.Builder
+	for _, part := range m {
+		if part.Kind == TextPart {
+			b.WriteString(part.Text)
+			continue
+		}
+		b.WriteString("**" + part.Text + "**")
+	}
+	return b.String()
+}
+
+func (m Message) Plain() string {
+	var b strings.Builder
+	for _, part := range m {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+func text(s string) MessagePart {
+	return MessagePart{Kind: TextPart, Text: s}
+}
+
+func names(kind PartKind, values []string) []MessagePart {
+	parts := []MessagePart{}
+	for i, v := range values {
+		if i > 0 {
+			parts = append(parts, text(", "))
+		}
+		parts = append(parts, MessagePart{Kind: kind, Text: v})
+	}
+	return parts
+}
+
+func messagePartRuleName(kind PartKind) string {
+	switch kind {
+	case ComponentPart:
+		return "error-message-comp"
+	case ParamPart:
+		return "error-message-param"
+	default:
+		return "error-message-text"
+	}
+}
+
+func messagePartNodes(parent ast.Node, msg Message) []ast.Node {
+	nodes := make([]ast.Node, 0, len(msg))
+	for _, part := range msg {
+		partNode := ast.DefaultEmptyNode()
+		partNode.SetRule(rule.NewDynamic(messagePartRuleName(part.Kind)))
+		partNode.SetParent(parent)
+		partNode.SetRaw([]byte(part.Text))
+		nodes = append(nodes, partNode)
+	}
+	return nodes
+}
+
+// MessageOf reads the message parts of a block-error or inline-error node.
+func MessageOf(errNode ast.Node) Message {
+	errMsg := ast.FindNodeByRuleName(errNode.Children(), "error-message")
+	if errMsg == nil {
+		return nil
+	}
+
+	msg := Message{}
+	for _, child := range errMsg.Children() {
+		switch child.Rule().Name() {
+		case "error-message-comp":
+			msg = append(msg, MessagePart{Kind: ComponentPart, Text: string(child.Raw())})
+		case "error-message-param":
+			msg = append(msg, MessagePart{Kind: ParamPart, Text: string(child.Raw())})
+		case "error-message-text":
+			msg = append(msg, text(string(child.Raw())))
+		}
+	}
+
+	if len(msg) == 0 && len(errMsg.Raw()) > 0 {
+		msg = append(msg, text(strings.TrimSpace(string(errMsg.Raw()))))
+	}
+
+	return msg
+}
@@ -0,0 +1,114 @@
+package errwrap
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/util"
+)
+
+const maxSuggestions = 3
+
+// suggest returns the candidates close enough to name to be a likely typo,
+// nearest first.
+func suggest(name string, candidates []string) []string {
+	maxDist := utf8.RuneCountInString(name) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+
+	type scored struct {
+		name string
+		dist int
+	}
+
+	matches := []scored{}
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if candidate == "" || candidate == name || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		dist := util.Levenshtein(name, candidate)
+		if dist > maxDist {
+			continue
+		}
+		matches = append(matches, scored{name: candidate, dist: dist})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	result := []string{}
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		result = append(result, matches[i].name)
+	}
+	return result
+}
+
+func suggestForEach(names []string, candidates []string) []string {
+	result := []string{}
+	for _, name := range names {
+		result = appendUniqueStrings(result, suggest(name, candidates)...)
+	}
+	return result
+}
+
+// visibleCompNames returns the names of the components node can call: the
+// local definitions of its source, the registered globals and the builtins.
+func visibleCompNames(root ast.Node, node ast.Node) []string {
+	localCompDefSrc := root
+	globalCompDefAnc := ast.FindNode(ast.GetAncestors(node), func(anc ast.Node) bool {
+		return ast.IsRuleName(anc, "global-comp-def")
+	})
+	if globalCompDefAnc != nil {
+		localCompDefSrc = globalCompDefAnc
+	}
+
+	names := []string{}
+
+	localCompDefWrapper := ast.FindNodeByRuleName(localCompDefSrc.Children(), "local-comp-def-wrapper")
+	if localCompDefWrapper != nil {
+		for _, localCompDef := range localCompDefWrapper.Children() {
+			head := ast.GetCompDefHeadFromCompDef(localCompDef)
+			if head == nil {
+				continue
+			}
+			names = append(names, rawOfChild(head, "local-comp-name"))
+		}
+	}
+
+	globalCompDefWrapper := ast.FindNodeByRuleName(root.Children(), "global-comp-def-wrapper")
+	if globalCompDefWrapper != nil {
+		for _, globalCompDef := range globalCompDefWrapper.Children() {
+			names = append(names, rawOfChild(globalCompDef, "global-comp-name"))
+		}
+	}
+
+	builtinWrapper := ast.FindNodeByRuleName(root.Children(), "builtin-comp-wrapper")
+	if builtinWrapper != nil {
+		for _, builtinComp := range builtinWrapper.Children() {
+			names = append(names, rawOfChild(builtinComp, "builtin-comp-name"))
+		}
+	}
+
+	return names
+}
+
+// visibleParamNames returns the parameters a param-ref can refer to: those of
+// its local definition and, inside a global component, the global ones.
+func visibleParamNames(paramRef ast.Node) []string {
+	names := []string{}
+	for _, anc := range ast.GetAncestors(paramRef) {
+		if ast.IsRuleNameOneOf(anc, []string{"local-comp-def", "global-comp-def"}) {
+			names = append(names, getCompDefParamNames(anc)...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,126 @@
+package errwrap
+
+import (
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/builtin"
+	"github.com/umono-cms/compono/urlpolicy"
+)
+
+func unsafeLink() wrapRule {
+	return wrapRule{
+		code: CodeUnsafeURL,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleName("link"),
+			hasReportedURL(),
+		},
+		title:   TitleUnsafeURL,
+		message: unsafeURLMsg,
+		block:   neverBlock,
+	}
+}
+
+func unsafeURLArg() wrapRule {
+	return wrapRule{
+		code: CodeUnsafeURL,
+		conditions: []func(*wrapContext, ast.Node) bool{
+			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
+			hasReportedURL(),
+		},
+		title:   TitleUnsafeURL,
+		message: unsafeURLMsg,
+		block:   blockFromRuleName,
+	}
+}
+
+func hasReportedURL() func(*wrapContext, ast.Node) bool {
+	return func(ctx *wrapContext, node ast.Node) bool {
+		if ctx.urlPolicy == nil || ctx.urlPolicy.Action != urlpolicy.Report {
+			return false
+		}
+		return len(getRejectedURLs(ctx, node)) > 0
+	}
+}
+
+func unsafeURLMsg(ctx *wrapContext, node ast.Node) localizable {
+	urls := []string{}
+	for _, url := range getRejectedURLs(ctx, node) {
+		urls = append(urls, strings.TrimSpace(string(url.Raw())))
+	}
+	return localize(MsgUnsafeURL, textArg(urls...))
+}
+
+// getRejectedURLs returns the nodes holding the URLs of a link, or of the
+// URL parameters of a builtin component call, that the URL policy rejects.
+func getRejectedURLs(ctx *wrapContext, node ast.Node) []ast.Node {
+	rejected := []ast.Node{}
+	for _, url := range getURLs(ctx, node) {
+		if !ctx.urlPolicy.Allows(strings.TrimSpace(string(url.Raw()))) {
+			rejected = append(rejected, url)
+		}
+	}
+	return rejected
+}
+
+func getURLs(ctx *wrapContext, node ast.Node) []ast.Node {
+	if ast.IsRuleName(node, "link") {
+		linkURL := ast.FindNodeByRuleName(node.Children(), "link-url")
+		if linkURL == nil {
+			return nil
+		}
+		return []ast.Node{linkURL}
+	}
+
+	compDef := findCompDef(ctx.root, node, getCompCallNameStr(node))
+	if compDef == nil || !ast.IsRuleName(compDef, "builtin-comp") {
+		return nil
+	}
+
+	urlParams := map[string]bool{}
+	name := rawOfChild(compDef, "builtin-comp-name")
+	for _, comp := range builtin.BuiltinComponents() {
+		if comp.Name != name {
+			continue
+		}
+		for _, param := range comp.Params {
+			if param.URL {
+				urlParams[param.Name] = true
+			}
+		}
+	}
+
+	urls := []ast.Node{}
+	for _, arg := range ast.GetCompCallArgsFromCompCall(node) {
+		if !ast.IsRuleName(arg, "comp-call-arg") || ast.GetTypeFromCompCallArg(arg) != "string" {
+			continue
+		}
+		if !urlParams[ast.GetArgNameFromCompCallArg(arg)] {
+			continue
+		}
+		compCallArgType := ast.FindNodeByRuleName(arg.Children(), "comp-call-arg-type")
+		value := ast.FindNodeByRuleName(compCallArgType.Children()[0].Children(), "comp-call-arg-value")
+		if value != nil {
+			urls = append(urls, value)
+		}
+	}
+	return urls
+}
+
+// replaceRejectedURLs replaces the URLs the URL policy rejects in the links
+// and builtin component calls of node's tree with the policy's
+// replacement.
+func replaceRejectedURLs(ctx *wrapContext, node ast.Node) {
+	if ctx.urlPolicy == nil || ctx.urlPolicy.Action != urlpolicy.Replace {
+		return
+	}
+
+	nodes := ast.FilterNodesInTree(node, func(n ast.Node) bool {
+		return ast.IsRuleNameOneOf(n, []string{"link", "block-comp-call", "inline-comp-call"})
+	})
+	for _, n := range nodes {
+		for _, url := range getRejectedURLs(ctx, n) {
+			url.SetRaw([]byte(ctx.urlPolicy.Replacement))
+		}
+	}
+}
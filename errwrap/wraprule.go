@@ -5,6 +5,9 @@ import (
 	"strings"
 
 	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/builtin"
+	"github.com/umono-cms/compono/links"
+	"github.com/umono-cms/compono/urlpolicy"
 	"github.com/umono-cms/compono/util"
 )
 
@@ -16,15 +19,21 @@ type compParamInfo struct {
 
 type wrapContext struct {
 	root               ast.Node
+	locale             string
 	compCallChains     [][]ast.Node
 	compCallCycleCache map[ast.Node]bool
 	paramCycleClosers  map[ast.Node]string
+	layoutCycleDecls   map[ast.Node]bool
+	urlPolicy          *urlpolicy.Policy
+	linkResolver       links.Resolver
+	resolvedURLs       map[ast.Node]resolvedURL
 }
 
 type wrapRule struct {
+	code       Code
 	conditions []func(ctx *wrapContext, node ast.Node) bool
-	title      func(ctx *wrapContext, node ast.Node) string
-	message    func(ctx *wrapContext, node ast.Node) string
+	title      MessageID
+	message    func(ctx *wrapContext, node ast.Node) localizable
 	block      func(ctx *wrapContext, node ast.Node) bool
 }
 
@@ -45,16 +54,26 @@ func wrapRules() []wrapRule {
 		undefinedParamRef(),
 		notCompParamCompCall(),
 		undefinedParamCompCall(),
+		misplacedLayout(),
+		unknownLayout(),
+		infiniteLayout(),
+		repeatedContent(),
+		contentOutsideLayout(),
+		brokenLink(),
+		brokenURLArg(),
+		unsafeLink(),
+		unsafeURLArg(),
 	}
 }
 
 func infiniteBlockCompCallByItself() wrapRule {
 	return wrapRule{
+		code: CodeInfiniteCompCall,
 		conditions: []func(_ *wrapContext, node ast.Node) bool{
 			isRuleName("block-comp-call"),
 			isCalledByItself(),
 		},
-		title:   staticTitle("Infinite component call"),
+		title:   TitleInfiniteCompCall,
 		message: infiniteCompCallMsg,
 		block:   alwaysBlock,
 	}
@@ -62,11 +81,12 @@ func infiniteBlockCompCallByItself() wrapRule {
 
 func infiniteInlineCompCallByItself() wrapRule {
 	return wrapRule{
+		code: CodeInfiniteCompCall,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleName("inline-comp-call"),
 			isCalledByItself(),
 		},
-		title:   staticTitle("Infinite component call"),
+		title:   TitleInfiniteCompCall,
 		message: infiniteCompCallMsg,
 		block:   neverBlock,
 	}
@@ -74,12 +94,13 @@ func infiniteInlineCompCallByItself() wrapRule {
 
 func infiniteCompCallByChain() wrapRule {
 	return wrapRule{
+		code: CodeInfiniteCompCall,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
 			isKnownComponent(),
 			isCalledByChain(),
 		},
-		title:   staticTitle("Infinite component call"),
+		title:   TitleInfiniteCompCall,
 		message: infiniteCompCallMsg,
 		block:   blockFromRuleName,
 	}
@@ -87,12 +108,13 @@ func infiniteCompCallByChain() wrapRule {
 
 func infiniteCompCallByParam() wrapRule {
 	return wrapRule{
+		code: CodeInfiniteCompCall,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
 			isKnownComponent(),
 			takesItselfAsArgOrDefault(),
 		},
-		title:   staticTitle("Infinite component call"),
+		title:   TitleInfiniteCompCall,
 		message: infiniteCompCallMsg,
 		block:   blockFromRuleName,
 	}
@@ -100,12 +122,13 @@ func infiniteCompCallByParam() wrapRule {
 
 func infiniteParamCompCallByChain() wrapRule {
 	return wrapRule{
+		code: CodeInfiniteCompCall,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleName("param-ref"),
 			isCompParamRefNode(),
 			isClosingParamCompCallInCycle(),
 		},
-		title:   staticTitle("Infinite component call"),
+		title:   TitleInfiniteCompCall,
 		message: infiniteParamCompCallMsg,
 		block:   blockForParamRef,
 	}
@@ -113,11 +136,12 @@ func infiniteParamCompCallByChain() wrapRule {
 
 func unknownCompCall() wrapRule {
 	return wrapRule{
+		code: CodeUnknownComp,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
 			isUnknownComponent(),
 		},
-		title:   staticTitle("Unknown component"),
+		title:   TitleUnknownComp,
 		message: unknownCompCallMsg,
 		block:   blockFromRuleName,
 	}
@@ -125,12 +149,13 @@ func unknownCompCall() wrapRule {
 
 func unknownCompParamCall() wrapRule {
 	return wrapRule{
+		code: CodeUnknownComp,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
 			isKnownComponent(),
 			hasUnknownResolvedCompArg(),
 		},
-		title:   staticTitle("Unknown component"),
+		title:   TitleUnknownComp,
 		message: unknownCompParamCallMsg,
 		block:   blockFromRuleName,
 	}
@@ -138,12 +163,13 @@ func unknownCompParamCall() wrapRule {
 
 func blockCompInsideInline() wrapRule {
 	return wrapRule{
+		code: CodeInvalidCompUsage,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleName("inline-comp-call"),
 			isKnownComponent(),
 			callsBlockComponent(),
 		},
-		title:   staticTitle("Invalid component usage"),
+		title:   TitleInvalidCompUsage,
 		message: blockCompInsideInlineMsg,
 		block:   neverBlock,
 	}
@@ -151,12 +177,13 @@ func blockCompInsideInline() wrapRule {
 
 func blockParamCompInsideInline() wrapRule {
 	return wrapRule{
+		code: CodeInvalidCompUsage,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
 			isKnownComponent(),
 			resolvedCompArgUsedAsInlineButIsBlock(),
 		},
-		title:   staticTitle("Invalid component usage"),
+		title:   TitleInvalidCompUsage,
 		message: blockParamCompInsideInlineMsg,
 		block:   blockFromRuleName,
 	}
@@ -164,12 +191,13 @@ func blockParamCompInsideInline() wrapRule {
 
 func undefinedParam() wrapRule {
 	return wrapRule{
+		code: CodeUnknownParam,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
 			isKnownComponent(),
 			hasUndefinedArgs(),
 		},
-		title:   staticTitle("Unknown parameter"),
+		title:   TitleUnknownParam,
 		message: undefinedParamMsg,
 		block:   blockFromRuleName,
 	}
@@ -177,12 +205,13 @@ func undefinedParam() wrapRule {
 
 func wrongArgType() wrapRule {
 	return wrapRule{
+		code: CodeWrongArgType,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleNameOneOf("block-comp-call", "inline-comp-call"),
 			isKnownComponent(),
 			hasWrongTypeArgs(),
 		},
-		title:   staticTitle("Wrong argument type"),
+		title:   TitleWrongArgType,
 		message: wrongArgTypeMsg,
 		block:   blockFromRuleName,
 	}
@@ -190,11 +219,12 @@ func wrongArgType() wrapRule {
 
 func paramRefInRootContent() wrapRule {
 	return wrapRule{
+		code: CodeInvalidParamUsage,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleName("param-ref"),
 			isInsideRootContent(),
 		},
-		title:   staticTitle("Invalid parameter usage"),
+		title:   TitleInvalidParamUsage,
 		message: paramRefInRootMsg,
 		block:   neverBlock,
 	}
@@ -202,12 +232,13 @@ func paramRefInRootContent() wrapRule {
 
 func undefinedParamRef() wrapRule {
 	return wrapRule{
+		code: CodeUnknownParam,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleName("param-ref"),
 			not(isInsideRootContent()),
 			isUndefinedParamRef(),
 		},
-		title:   staticTitle("Unknown parameter"),
+		title:   TitleUnknownParam,
 		message: undefinedParamRefMsg,
 		block:   blockUndefinedParamRef,
 	}
@@ -215,13 +246,14 @@ func undefinedParamRef() wrapRule {
 
 func undefinedParamCompCall() wrapRule {
 	return wrapRule{
+		code: CodeUnknownParam,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleName("param-ref"),
 			hasCompCallArgs(),
 			not(isInsideRootContent()),
 			isUndefinedParamCompCall(),
 		},
-		title:   staticTitle("Unknown parameter"),
+		title:   TitleUnknownParam,
 		message: undefinedParamCompCallAsUnknownMsg,
 		block:   blockForParamRef,
 	}
@@ -229,13 +261,14 @@ func undefinedParamCompCall() wrapRule {
 
 func notCompParamCompCall() wrapRule {
 	return wrapRule{
+		code: CodeNotCompParam,
 		conditions: []func(*wrapContext, ast.Node) bool{
 			isRuleName("param-ref"),
 			any(hasCompCallArgs(), isLegacyNotCompStandalone()),
 			not(isInsideRootContent()),
 			isNotCompParamCompCall(),
 		},
-		title:   staticTitle("Not component parameter"),
+		title:   TitleNotCompParam,
 		message: notCompParamCompCallMsg,
 		block:   blockForParamRef,
 	}
@@ -286,16 +319,12 @@ func blockUndefinedParamRef(_ *wrapContext, node ast.Node) bool {
 	return strings.Contains(refName, "comp")
 }
 
-func staticTitle(s string) func(*wrapContext, ast.Node) string {
-	return func(_ *wrapContext, _ ast.Node) string { return s }
-}
-
-func infiniteCompCallMsg(_ *wrapContext, node ast.Node) string {
+func infiniteCompCallMsg(_ *wrapContext, node ast.Node) localizable {
 	name := getCompCallNameStr(node)
-	return "The call to component **" + name + "** creates an infinite loop and was skipped."
+	return localize(MsgInfiniteCompCall, compArg(name))
 }
 
-func infiniteParamCompCallMsg(ctx *wrapContext, node ast.Node) string {
+func infiniteParamCompCallMsg(ctx *wrapContext, node ast.Node) localizable {
 	name := getClosingParamCompCallTargetName(ctx, node)
 	if name == "" {
 		name = getParamCompCallNameStr(node)
@@ -303,98 +332,104 @@ func infiniteParamCompCallMsg(ctx *wrapContext, node ast.Node) string {
 	if strings.HasPrefix(name, "NODE_") {
 		name = strings.TrimPrefix(name, "NODE_")
 	}
-	return "The call to component **" + name + "** creates an infinite loop and was skipped."
+	return localize(MsgInfiniteCompCall, compArg(name))
 }
 
-func unknownCompCallMsg(_ *wrapContext, node ast.Node) string {
+func unknownCompCallMsg(ctx *wrapContext, node ast.Node) localizable {
 	name := getCompCallNameStr(node)
-	return "The component **" + name + "** is not defined or not registered."
+	return notDefinedCompsMsg([]string{name}).
+		withSuggestions(compArg(suggest(name, visibleCompNames(ctx.root, node))...))
 }
 
-func unknownCompParamCallMsg(ctx *wrapContext, compCall ast.Node) string {
+func unknownCompParamCallMsg(ctx *wrapContext, compCall ast.Node) localizable {
 	compCallName := getCompCallNameStr(compCall)
 	compDef := findCompDef(ctx.root, compCall, compCallName)
 	if compDef == nil {
-		return "The component **" + compCallName + "** is not defined or not registered."
+		return notDefinedCompsMsg([]string{compCallName})
 	}
 
 	unknowns := getUnknownResolvedCompArgs(ctx, compCall, compDef)
 
 	if len(unknowns) == 0 {
-		return "The component **" + compCallName + "** is not defined or not registered."
+		return notDefinedCompsMsg([]string{compCallName})
 	}
 
-	if len(unknowns) == 1 {
-		return "The component **" + unknowns[0] + "** is not defined or not registered."
-	}
+	return notDefinedCompsMsg(unknowns)
+}
 
-	var prepared string
-	for i, unk := range unknowns {
-		prepared += "**" + unk + "**"
-		if i != len(unknowns)-1 {
-			prepared += ", "
-		}
+func notDefinedCompsMsg(compNames []string) localizable {
+	if len(compNames) == 1 {
+		return localize(MsgUnknownComp, compArg(compNames[0]))
 	}
-
-	return "The components " + prepared + "are not defined or not registered."
+	return localize(MsgUnknownComps, compArg(compNames...))
 }
 
-func blockCompInsideInlineMsg(_ *wrapContext, node ast.Node) string {
+func blockCompInsideInlineMsg(_ *wrapContext, node ast.Node) localizable {
 	name := getCompCallNameStr(node)
-	return "The component **" + name + "** is a block component and cannot be used inline."
+	return localize(MsgBlockCompInInline, compArg(name))
 }
 
-func blockParamCompInsideInlineMsg(ctx *wrapContext, node ast.Node) string {
+func blockParamCompInsideInlineMsg(ctx *wrapContext, node ast.Node) localizable {
 	name := getResolvedInlineBlockCompName(ctx, node)
 	if name == "" {
 		name = getCompCallNameStr(node)
 	}
-	return "The component **" + name + "** is a block component and cannot be used inline."
+	return localize(MsgBlockCompInInline, compArg(name))
 }
 
-func undefinedParamMsg(ctx *wrapContext, node ast.Node) string {
+func undefinedParamMsg(ctx *wrapContext, node ast.Node) localizable {
 	undefinedArgNames := getUndefinedArgNames(ctx, node)
 	if len(undefinedArgNames) == 0 {
-		return "One or more parameters are not defined for this component."
+		return localize(MsgSomeUndefinedParam)
 	}
 
-	if len(undefinedArgNames) == 1 {
-		return "The parameter **" + undefinedArgNames[0] + "** is not defined for this component."
+	msg := notDefinedParamsMsg(undefinedArgNames)
+
+	compDef := findCompDef(ctx.root, node, getCompCallNameStr(node))
+	if compDef == nil {
+		return msg
 	}
+	return msg.withSuggestions(paramArg(suggestForEach(undefinedArgNames, getCompDefParamNames(compDef))...))
+}
 
-	return "The parameters **" + strings.Join(undefinedArgNames, "**, **") + "** are not defined for this component."
+func notDefinedParamsMsg(paramNames []string) localizable {
+	if len(paramNames) == 1 {
+		return localize(MsgUndefinedParam, paramArg(paramNames[0]))
+	}
+	return localize(MsgUndefinedParams, paramArg(paramNames...))
 }
 
-func wrongArgTypeMsg(ctx *wrapContext, node ast.Node) string {
+func wrongArgTypeMsg(ctx *wrapContext, node ast.Node) localizable {
 	wrongTypeArgNames := getWrongTypeArgNames(ctx, node)
 	if len(wrongTypeArgNames) == 0 {
-		return "One or more arguments have the wrong type for this component."
+		return localize(MsgSomeWrongArgType)
 	}
 
 	if len(wrongTypeArgNames) == 1 {
-		return "The parameter **" + wrongTypeArgNames[0] + "** has the wrong type."
+		return localize(MsgWrongArgType, paramArg(wrongTypeArgNames[0]))
 	}
 
-	return "The parameters **" + strings.Join(wrongTypeArgNames, "**, **") + "** have the wrong type."
+	return localize(MsgWrongArgTypes, paramArg(wrongTypeArgNames...))
 }
 
-func paramRefInRootMsg(_ *wrapContext, _ ast.Node) string {
-	return "Parameters cannot be used in the root context."
+func paramRefInRootMsg(_ *wrapContext, _ ast.Node) localizable {
+	return localize(MsgParamRefInRoot)
 }
 
-func undefinedParamRefMsg(_ *wrapContext, node ast.Node) string {
+func undefinedParamRefMsg(_ *wrapContext, node ast.Node) localizable {
 	refName := getParamRefNameStr(node)
-	return "The parameter **" + refName + "** is not defined for this component."
+	return notDefinedParamsMsg([]string{refName}).
+		withSuggestions(paramArg(suggest(refName, visibleParamNames(node))...))
 }
 
-func undefinedParamCompCallAsUnknownMsg(_ *wrapContext, node ast.Node) string {
+func undefinedParamCompCallAsUnknownMsg(_ *wrapContext, node ast.Node) localizable {
 	name := getParamCompCallNameStr(node)
-	return "The parameter **" + name + "** is not defined for this component."
+	return notDefinedParamsMsg([]string{name})
 }
 
-func notCompParamCompCallMsg(_ *wrapContext, node ast.Node) string {
+func notCompParamCompCallMsg(_ *wrapContext, node ast.Node) localizable {
 	name := getParamCompCallNameStr(node)
-	return "The parameter **" + name + "** is not component parameter"
+	return localize(MsgNotCompParam, paramArg(name))
 }
 
 func isRuleName(name string) func(*wrapContext, ast.Node) bool {
@@ -555,8 +590,10 @@ func compCallHasCycle(ctx *wrapContext, compCall ast.Node) bool {
 		return false
 	}
 
-	var dfs func(callNode ast.Node, path []string) bool
-	dfs = func(callNode ast.Node, path []string) bool {
+	// the path holds definitions rather than names, as the same name can
+	// refer to different components in different namespaces
+	var dfs func(callNode ast.Node, path []ast.Node) bool
+	dfs = func(callNode ast.Node, path []ast.Node) bool {
 		callName := getCompCallNameStr(callNode)
 		if callName == "" {
 			return false
@@ -576,14 +613,17 @@ func compCallHasCycle(ctx *wrapContext, compCall ast.Node) bool {
 			return ast.IsRuleNameOneOf(node, []string{"block-comp-call", "inline-comp-call"})
 		})
 
-		nextPath := append(append([]string{}, path...), callName)
+		nextPath := append(append([]ast.Node{}, path...), def)
 		for _, child := range children {
 			childName := getCompCallNameStr(child)
 			if childName == "" {
 				continue
 			}
 
-			if util.InSliceString(childName, nextPath) {
+			childDef := findCompDef(ctx.root, child, childName)
+			if childDef != nil && ast.FindNode(nextPath, func(pathDef ast.Node) bool {
+				return pathDef == childDef
+			}) != nil {
 				return true
 			}
 
@@ -910,7 +950,7 @@ func callsBlockComponent() func(*wrapContext, ast.Node) bool {
 			return false
 		}
 
-		return isBlockComponent(compDef)
+		return IsBlockComponent(compDef)
 	}
 }
 
@@ -973,7 +1013,7 @@ func getResolvedInlineBlockCompName(ctx *wrapContext, compCall ast.Node) string
 			continue
 		}
 
-		if isBlockComponent(argCompDef) {
+		if IsBlockComponent(argCompDef) {
 			return resolvedCompName
 		}
 
@@ -1524,20 +1564,36 @@ func isInlineParamRefNode(node ast.Node) bool {
 	}) != nil
 }
 
-func isBlockComponent(compDef ast.Node) bool {
+// IsBlockComponent reports whether the content of compDef has more than
+// one line or block element, so that it cannot be called inline.
+func IsBlockComponent(compDef ast.Node) bool {
+	if ast.IsRuleName(compDef, "builtin-comp") {
+		name := rawOfChild(compDef, "builtin-comp-name")
+		for _, comp := range builtin.BuiltinComponents() {
+			if comp.Name == name {
+				return !comp.InlineRenderable
+			}
+		}
+		return false
+	}
+
 	compDefContent := getCompDefContent(compDef)
 	if compDefContent == nil {
 		return false
 	}
 
-	childrenCount := len(compDefContent.Children())
+	children := ast.FilterNodes(compDefContent.Children(), func(child ast.Node) bool {
+		return !ast.IsRuleName(child, "comment")
+	})
+
+	childrenCount := len(children)
 	if childrenCount == 0 {
 		return false
 	} else if childrenCount > 1 {
 		return true
 	}
 
-	p := ast.FindNodeByRuleName(compDefContent.Children(), "p")
+	p := ast.FindNodeByRuleName(children, "p")
 	if p == nil {
 		return true
 	}
@@ -1563,7 +1619,7 @@ func findCompDef(root ast.Node, compCallNode ast.Node, name string) ast.Node {
 		return localCompDef
 	}
 
-	globalCompDef := ast.FindGlobalCompDef(root, name)
+	globalCompDef := ast.ResolveGlobalCompDef(root, compCallNode, name)
 	if globalCompDef != nil {
 		return globalCompDef
 	}
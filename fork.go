@@ -0,0 +1,94 @@
+package compono
+
+import (
+	"github.com/umono-cms/compono/ast"
+)
+
+// Fork returns a child instance for a narrower scope, such as a site over a
+// base theme or a section over a site. The child sees the global components
+// of its parent and can shadow them, but registering, replacing or
+// unregistering on the child never changes the parent. Global components
+// resolve from the child's own layer to its parent's, before builtins, and
+// diagnostics and component infos report the layer of the definition.
+//
+// The child starts with the parent's settings and shares its parser,
+// renderer, validator, error wrapper and logger. They keep no state between
+// conversions, so the layers convert in parallel, but they must be treated
+// as read-only: an override or catalog set on a shared one applies to every
+// layer. A child that renders differently gets its own with SetRenderer or
+// SetErrorWrapper. The stores of every layer are searched, the child's
+// first, for components no layer registered.
+func (c *compono) Fork(layer string) Compono {
+	child := &compono{
+		parser:         c.parser,
+		renderer:       c.renderer,
+		validator:      c.validator,
+		errorWrapper:   c.errorWrapper,
+		logger:         c.logger,
+		globalWrapper:  c.newGlobalWrapper(nil),
+		builtinWrapper: c.builtinWrapper,
+		strict:         c.strict,
+		locale:         c.locale,
+		warnings:       c.warnings,
+		urlPolicy:      c.urlPolicy,
+		linkResolver:   c.linkResolver,
+		parent:         c,
+		layer:          layer,
+	}
+
+	return child
+}
+
+// withParentGlobals appends to globalCompDefs copies of the global
+// components of the parent layers they do not shadow.
+func (c *compono) withParentGlobals(globalCompDefs []ast.Node) []ast.Node {
+	if c.parent == nil {
+		return globalCompDefs
+	}
+
+	shadowed := map[string]bool{}
+	for _, globalCompDef := range globalCompDefs {
+		shadowed[globalCompName(globalCompDef)] = true
+	}
+	for _, globalCompDef := range c.parent.cloneGlobalComponents() {
+		if !shadowed[globalCompName(globalCompDef)] {
+			globalCompDefs = append(globalCompDefs, globalCompDef)
+		}
+	}
+	return globalCompDefs
+}
+
+// layers returns this instance followed by the instances it was forked
+// from, nearest first.
+func (c *compono) layers() []*compono {
+	layers := []*compono{}
+	for layer := c; layer != nil; layer = layer.parent {
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+func (c *compono) hasStore() bool {
+	for _, layer := range c.layers() {
+		if layer.Store() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupLayeredStoredGlobal looks name up in the store of each layer,
+// nearest first.
+func (c *compono) lookupLayeredStoredGlobal(name string) (ast.Node, error) {
+	for _, layer := range c.layers() {
+		if layer.Store() == nil {
+			continue
+		}
+
+		node, err := layer.lookupStoredGlobal(name)
+		if err != nil || node != nil {
+			return node, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,298 @@
+// Package format rewrites compono source in its canonical style without
+// changing what it renders:
+//
+//   - component calls as {{ NAME arg="x" other=1 }} and parameter
+//     references as {{ name }}
+//   - component heads as ~ NAME param="" other=0, with the parameters of
+//     the local component heads of a source starting at the same column,
+//     and the head line of a global component as param="" other=0
+//   - layout declarations as ~~ LAYOUT NAME
+//   - one blank line between blocks and before local component definitions
+//   - no blank lines at the start of a page and at the end, and a final
+//     newline
+//
+// Code blocks, inline code and comments are left as they are, and so are
+// constructs the parser does not recognize, such as a call whose arguments
+// are malformed. Formatting formatted source gives the same source.
+package format
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/logger"
+	"github.com/umono-cms/compono/parser"
+	"github.com/umono-cms/compono/rule"
+)
+
+const (
+	compName  = `[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*`
+	paramName = `[a-z][a-z0-9-]*`
+	// values are copied as written, so the alternatives only need to take
+	// the longest match: bools match as parameter names, numbers as
+	// component names
+	value = `"[^"\n]*"|` + compName + `|` + paramName
+)
+
+var (
+	callRe       = regexp.MustCompile(`^\{\{[ \t]*(` + compName + `|` + paramName + `)((?:[ \t]+` + paramName + `[ \t]*=[ \t]*(?:` + value + `))*)[ \t]*\}\}$`)
+	argRe        = regexp.MustCompile(`(` + paramName + `)[ \t]*=[ \t]*(` + value + `)`)
+	localHeadRe  = regexp.MustCompile(`~[ \t]+(` + compName + `)((?:[ \t]+` + paramName + `(?:[ \t]*=[ \t]*(?:` + value + `))?)*)[ \t]*$`)
+	globalHeadRe = regexp.MustCompile(`^(` + paramName + `[ \t]*=[ \t]*(?:` + value + `)(?:[ \t]+` + paramName + `[ \t]*=[ \t]*(?:` + value + `))*)[ \t]*$`)
+	paramRe      = regexp.MustCompile(`(` + paramName + `)(?:[ \t]*=[ \t]*(` + value + `))?`)
+	layoutDeclRe = regexp.MustCompile(`^~~[ \t]+LAYOUT[ \t]+(` + compName + `)[ \t]*`)
+)
+
+// Source formats the source of a page.
+func Source(source []byte) []byte {
+	return format(source, true, func(src []byte) ast.Node {
+		return parser.DefaultParser(logger.NewLogger()).Parse(src, ast.DefaultRootNode())
+	})
+}
+
+// Global formats the source of a global component, whose first line may be
+// its head. Blank lines at its start are collapsed but not removed, since
+// they turn the head into a paragraph.
+func Global(source []byte) []byte {
+	return format(source, false, func(src []byte) ast.Node {
+		node := ast.DefaultEmptyNode()
+		node.SetRule(rule.NewGlobalCompDef())
+		node.SetSource(ast.NewSource("", src))
+		return parser.DefaultParser(logger.NewLogger()).Parse(src, node)
+	})
+}
+
+func format(source []byte, trimStart bool, parse func([]byte) ast.Node) []byte {
+	if len(bytes.TrimSpace(source)) == 0 {
+		return []byte{}
+	}
+
+	formatted := rewrite(source, parse(source))
+	return spaceBlocks(formatted, parse(formatted), trimStart)
+}
+
+// edit replaces the bytes of a source between start and end.
+type edit struct {
+	start, end  int
+	replacement string
+}
+
+// rewrite writes the calls, references, heads and layout declarations of
+// root in their canonical form.
+func rewrite(source []byte, root ast.Node) []byte {
+	edits := []edit{}
+	width := headNameWidth(root)
+
+	walk(root, func(node ast.Node) bool {
+		switch {
+		case ast.IsRuleNameOneOf(node, []string{"code-block", "inline-code", "comment"}):
+			return false
+		case ast.IsRuleNameOneOf(node, []string{"block-comp-call", "inline-comp-call", "param-ref"}):
+			if e, ok := callEdit(node); ok {
+				edits = append(edits, e)
+			}
+			return false
+		case ast.IsRuleName(node, "local-comp-def-head"):
+			if e, ok := localHeadEdit(node, width); ok {
+				edits = append(edits, e)
+			}
+			return false
+		case ast.IsRuleName(node, "global-comp-def-head"):
+			if e, ok := globalHeadEdit(node); ok {
+				edits = append(edits, e)
+			}
+			return false
+		case ast.IsRuleName(node, "layout-decl"):
+			if e, ok := layoutDeclEdit(node); ok {
+				edits = append(edits, e)
+			}
+			return false
+		}
+		return true
+	})
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var out bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		if e.start < last {
+			continue
+		}
+		out.Write(source[last:e.start])
+		out.WriteString(e.replacement)
+		last = e.end
+	}
+	out.Write(source[last:])
+
+	return out.Bytes()
+}
+
+func walk(node ast.Node, visit func(ast.Node) bool) {
+	if node.Rule() != nil && !visit(node) {
+		return
+	}
+	for _, child := range node.Children() {
+		walk(child, visit)
+	}
+}
+
+// callEdit rewrites a component call or a parameter reference, which takes
+// arguments when the parameter is a component.
+func callEdit(node ast.Node) (edit, bool) {
+	start, end := node.Offsets()
+	m := callRe.FindSubmatch(node.Raw())
+	if m == nil || bytes.Count(node.Raw(), []byte("}}")) > 1 {
+		return edit{}, false
+	}
+
+	call := "{{ " + string(m[1])
+	for _, arg := range argRe.FindAllSubmatch(m[2], -1) {
+		call += " " + string(arg[1]) + "=" + string(arg[2])
+	}
+	return edit{start, end, call + " }}"}, true
+}
+
+// headNameWidth returns the length of the longest name among the local
+// component heads of root that have parameters, which are aligned to it.
+func headNameWidth(root ast.Node) int {
+	width := 0
+	walk(root, func(node ast.Node) bool {
+		if !ast.IsRuleName(node, "local-comp-def-head") {
+			return true
+		}
+		if _, m := localHead(node); m != nil && params(m[2]) != "" && len(m[1]) > width {
+			width = len(m[1])
+		}
+		return false
+	})
+	return width
+}
+
+// localHead matches the head line of a local component, which starts at
+// offset in the raw of node.
+func localHead(node ast.Node) (int, [][]byte) {
+	raw := bytes.TrimRight(node.Raw(), " \t\r\n")
+	lineStart := bytes.IndexByte(raw, '~')
+	if lineStart < 0 {
+		return 0, nil
+	}
+
+	line := raw[lineStart:]
+	m := localHeadRe.FindSubmatch(line)
+	if m == nil || len(m[0]) != len(line) {
+		return 0, nil
+	}
+	return lineStart, m
+}
+
+// localHeadEdit rewrites the head line of a local component, leaving the
+// newlines around it, which the head includes, to spaceBlocks. The
+// parameters of the heads start at the same column, after a name padded to
+// width.
+func localHeadEdit(node ast.Node, width int) (edit, bool) {
+	start, _ := node.Offsets()
+	lineStart, m := localHead(node)
+	if m == nil {
+		return edit{}, false
+	}
+
+	head := "~ " + string(m[1])
+	if written := params(m[2]); written != "" {
+		head += strings.Repeat(" ", width-len(m[1])) + written
+	}
+	return edit{start + lineStart, start + lineStart + len(m[0]), head}, true
+}
+
+func globalHeadEdit(node ast.Node) (edit, bool) {
+	start, _ := node.Offsets()
+	raw := bytes.TrimRight(node.Raw(), "\r\n")
+	m := globalHeadRe.FindSubmatch(raw)
+	if m == nil {
+		return edit{}, false
+	}
+	return edit{start, start + len(raw), params(m[1])[1:]}, true
+}
+
+// params writes each parameter of a head with a leading space.
+func params(source []byte) string {
+	written := ""
+	for _, param := range paramRe.FindAllSubmatch(source, -1) {
+		written += " " + string(param[1])
+		if param[2] != nil {
+			written += "=" + string(param[2])
+		}
+	}
+	return written
+}
+
+func layoutDeclEdit(node ast.Node) (edit, bool) {
+	start, _ := node.Offsets()
+	m := layoutDeclRe.FindSubmatch(node.Raw())
+	if m == nil {
+		return edit{}, false
+	}
+	return edit{start, start + len(m[0]), "~~ LAYOUT " + string(m[1])}, true
+}
+
+// spaceBlocks collapses the blank lines between blocks to one, puts one
+// blank line before every local component definition, above its doc
+// comments, and trims the blank lines at the end of source, and at its
+// start with trimStart. Newlines inside code are kept.
+func spaceBlocks(source []byte, root ast.Node, trimStart bool) []byte {
+	code := [][2]int{}
+	// a definition starts at its doc comments, or at its head
+	defs := map[int]bool{}
+
+	walk(root, func(node ast.Node) bool {
+		start, end := node.Offsets()
+		switch {
+		case ast.IsRuleNameOneOf(node, []string{"code-block", "inline-code"}):
+			code = append(code, [2]int{start, end})
+			return false
+		case ast.IsRuleName(node, "local-comp-def"):
+			defs[start+len(node.Raw())-len(bytes.TrimLeft(node.Raw(), "\n"))] = true
+		}
+		return true
+	})
+
+	inCode := func(i int) bool {
+		for _, c := range code {
+			if i >= c[0] && i < c[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out bytes.Buffer
+	for i := 0; i < len(source); {
+		if source[i] != '\n' || inCode(i) {
+			out.WriteByte(source[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(source) && source[j] == '\n' && !inCode(j) {
+			j++
+		}
+
+		switch {
+		case i == 0 && trimStart, j == len(source):
+		case defs[j] || j-i > 2:
+			out.WriteString("\n\n")
+		default:
+			out.Write(source[i:j])
+		}
+		i = j
+	}
+
+	out.WriteByte('\n')
+	return out.Bytes()
+}
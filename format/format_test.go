@@ -0,0 +1,81 @@
+package format_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umono-cms/compono/format"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "calls",
+			source: "{{CARD   title = \"a  b\" count=1 }}\n\nHi {{ USER name =name}}!",
+			want:   "{{ CARD title=\"a  b\" count=1 }}\n\nHi {{ USER name=name }}!\n",
+		},
+		{
+			name:   "heads",
+			source: "{{ A }}\n~ A   title = \"\"  flag\n{{title}}\n\n\n\n~ B\nb",
+			want:   "{{ A }}\n\n~ A title=\"\" flag\n{{ title }}\n\n~ B\nb\n",
+		},
+		{
+			name:   "aligned heads",
+			source: "{{ CARD }}\n\n~ CARD title=\"\"\nc\n\n~ NOTE\nn\n\n~ BANNER  text=\"\" size=1\nb",
+			want:   "{{ CARD }}\n\n~ CARD   title=\"\"\nc\n\n~ NOTE\nn\n\n~ BANNER text=\"\" size=1\nb\n",
+		},
+		{
+			name:   "parameter components",
+			source: "{{ A }}\n\n~ A comp = B\n{{  comp  x = 1 }}",
+			want:   "{{ A }}\n\n~ A comp=B\n{{ comp x=1 }}\n",
+		},
+		{
+			name:   "blank lines",
+			source: "\n\n# Title\n\n\n\nText\nmore\n\n\n",
+			want:   "# Title\n\nText\nmore\n",
+		},
+		{
+			name:   "code",
+			source: "```\n{{X}}\n\n\n\n~~  LAYOUT  A\n```\n\n`{{X}}`",
+			want:   "```\n{{X}}\n\n\n\n~~  LAYOUT  A\n```\n\n`{{X}}`\n",
+		},
+		{
+			name:   "comments and layouts",
+			source: "~~  LAYOUT   BASE  \n%% {{X}}   kept\nText",
+			want:   "~~ LAYOUT BASE\n%% {{X}}   kept\nText\n",
+		},
+		{
+			name:   "component docs",
+			source: "{{ A }}\n%% Card.\n%% @param title  Title.\n~ A title=\"\"\n{{ title }}\n%% B.\n~ B\nb",
+			want:   "{{ A }}\n\n%% Card.\n%% @param title  Title.\n~ A title=\"\"\n{{ title }}\n\n%% B.\n~ B\nb\n",
+		},
+		{
+			name:   "malformed calls",
+			source: "{{ A x = }}\n\n{{ B\n  x=1 }}",
+			want:   "{{ A x = }}\n\n{{ B\n  x=1 }}\n",
+		},
+		{
+			name:   "empty",
+			source: "\n \n",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := format.Source([]byte(tt.source))
+			assert.Equal(t, tt.want, string(got))
+			assert.Equal(t, tt.want, string(format.Source(got)))
+		})
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	assert.Equal(t, "title=\"\" count=0\n# {{ title }}\n", string(format.Global([]byte("title = \"\"   count = 0\n# {{title}}"))))
+	assert.Equal(t, "%% Card.\ntitle=\"\"\n{{ title }}\n", string(format.Global([]byte("%% Card.\ntitle = \"\"\n{{ title }}"))))
+	assert.Equal(t, "\n\ntitle = \"\"\n", string(format.Global([]byte("\n\n\n\ntitle = \"\""))))
+}
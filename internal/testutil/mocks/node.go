@@ -10,6 +10,9 @@ type node struct {
 	parent   ast.Node
 	children []ast.Node
 	raw      []byte
+	source   *ast.Source
+	start    int
+	end      int
 }
 
 func newNode(rule rulepkg.Rule, parent ast.Node, children []ast.Node, raw []byte) ast.Node {
@@ -60,6 +63,27 @@ func (n *node) SetRaw(raw []byte) {
 	n.raw = raw
 }
 
+func (n *node) Source() *ast.Source {
+	return n.source
+}
+
+func (n *node) SetSource(source *ast.Source) {
+	n.source = source
+}
+
+func (n *node) Offsets() (int, int) {
+	return n.start, n.end
+}
+
+func (n *node) SetOffsets(start, end int) {
+	n.start = start
+	n.end = end
+}
+
+func (n *node) Range() ast.Range {
+	return ast.RangeOf(n)
+}
+
 type nodeBuilder struct {
 	rule     rulepkg.Rule
 	parent   ast.Node
@@ -0,0 +1,227 @@
+package compono
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/builtin"
+	"github.com/umono-cms/compono/errwrap"
+)
+
+type ComponentKind string
+
+const (
+	KindBuiltin ComponentKind = "builtin"
+	KindGlobal  ComponentKind = "global"
+	KindLocal   ComponentKind = "local"
+)
+
+type ParamType string
+
+const (
+	ParamString    ParamType = "string"
+	ParamNumber    ParamType = "number"
+	ParamBool      ParamType = "bool"
+	ParamComponent ParamType = "comp"
+)
+
+// ComponentInfo describes a component for editors and tooling, such as
+// autocompletion and documentation pages. Descriptions come from the %%
+// comments directly above a local component head or at the top of a global
+// component source, where "%% @param name text" describes a parameter.
+// Layer names the forked instance a global component comes from.
+type ComponentInfo struct {
+	Name             string        `json:"name"`
+	Kind             ComponentKind `json:"kind"`
+	Description      string        `json:"description,omitempty"`
+	InlineRenderable bool          `json:"inlineRenderable"`
+	Params           []ParamInfo   `json:"params"`
+	Layer            string        `json:"layer,omitempty"`
+}
+
+// ParamInfo describes a component parameter. Default is a string, a
+// float64, a bool or, for a component parameter, the name of the component.
+type ParamInfo struct {
+	Name        string    `json:"name"`
+	Type        ParamType `json:"type"`
+	Default     any       `json:"default"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Components returns the builtin components, then the registered and
+// stored global components sorted by name, then the local components of
+// source in definition order. source may be nil.
+func (c *compono) Components(source []byte) ([]ComponentInfo, error) {
+	infos := []ComponentInfo{}
+
+	for _, comp := range builtin.BuiltinComponents() {
+		infos = append(infos, builtinComponentInfo(comp))
+	}
+
+	root, err := c.allGlobalsRoot()
+	if err != nil {
+		return nil, err
+	}
+	gw := ast.FindNodeByRuleName(root.Children(), "global-comp-def-wrapper")
+
+	globals := []ComponentInfo{}
+	for _, globalCompDef := range gw.Children() {
+		info := compDefInfo(globalCompName(globalCompDef), KindGlobal, globalCompDef)
+		if globalCompDef.Source() != nil {
+			info.Layer = globalCompDef.Source().Layer()
+		}
+		globals = append(globals, info)
+	}
+	sort.Slice(globals, func(i, j int) bool {
+		return globals[i].Name < globals[j].Name
+	})
+	infos = append(infos, globals...)
+
+	if len(source) == 0 {
+		return infos, nil
+	}
+
+	sourceRoot := c.Parser().Parse(source, ast.DefaultRootNode())
+	localCompDefWrapper := ast.FindNodeByRuleName(sourceRoot.Children(), "local-comp-def-wrapper")
+	if localCompDefWrapper == nil {
+		return infos, nil
+	}
+
+	defined := map[string]bool{}
+	for _, localCompDef := range localCompDefWrapper.Children() {
+		head := ast.GetCompDefHeadFromCompDef(localCompDef)
+		if head == nil {
+			continue
+		}
+		nameNode := ast.FindNodeByRuleName(head.Children(), "local-comp-name")
+		if nameNode == nil {
+			continue
+		}
+		name := strings.TrimSpace(string(nameNode.Raw()))
+		// only the first definition of a name is used
+		if name == "" || defined[name] {
+			continue
+		}
+		defined[name] = true
+		infos = append(infos, compDefInfo(name, KindLocal, localCompDef))
+	}
+
+	return infos, nil
+}
+
+func builtinComponentInfo(comp builtin.Component) ComponentInfo {
+	params := []ParamInfo{}
+	for _, param := range comp.Params {
+		params = append(params, ParamInfo{
+			Name:        param.Name,
+			Type:        builtinParamType(param.Type),
+			Default:     param.DefaultValue,
+			Description: param.Description,
+		})
+	}
+
+	return ComponentInfo{
+		Name:             comp.Name,
+		Kind:             KindBuiltin,
+		Description:      comp.Description,
+		InlineRenderable: comp.InlineRenderable,
+		Params:           params,
+	}
+}
+
+func builtinParamType(paramType builtin.ParamType) ParamType {
+	switch paramType {
+	case builtin.NumberType:
+		return ParamNumber
+	case builtin.BoolType:
+		return ParamBool
+	case builtin.ComponentType:
+		return ParamComponent
+	default:
+		return ParamString
+	}
+}
+
+func compDefInfo(name string, kind ComponentKind, compDef ast.Node) ComponentInfo {
+	doc := parseCompDoc(compDocLines(compDef))
+
+	params := []ParamInfo{}
+	for _, compParam := range ast.GetCompParamsFromCompDef(compDef) {
+		if !ast.IsRuleName(compParam, "comp-param") {
+			continue
+		}
+		paramType := ParamType(ast.GetTypeFromCompParam(compParam))
+		paramName := ast.GetParamNameFromCompParam(compParam)
+		params = append(params, ParamInfo{
+			Name:        paramName,
+			Type:        paramType,
+			Default:     typedDefault(paramType, compParamDefVal(compParam)),
+			Description: doc.params[paramName],
+		})
+	}
+
+	return ComponentInfo{
+		Name:             name,
+		Kind:             kind,
+		Description:      doc.description,
+		InlineRenderable: !errwrap.IsBlockComponent(compDef),
+		Params:           params,
+	}
+}
+
+func typedDefault(paramType ParamType, raw string) any {
+	switch paramType {
+	case ParamNumber:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return raw
+		}
+		return number
+	case ParamBool:
+		return raw == "true"
+	default:
+		return raw
+	}
+}
+
+type compDoc struct {
+	description string
+	params      map[string]string
+}
+
+// compDocLines returns the comment lines documenting compDef: those
+// directly above the head of a local component, or those at the top of a
+// global component source.
+func compDocLines(compDef ast.Node) []string {
+	docNode := ast.FindNodeByRuleName(compDef.Children(), "comp-doc")
+	if docNode == nil {
+		return nil
+	}
+	lines := []string{}
+	for _, comment := range docNode.Children() {
+		lines = append(lines, string(comment.Raw()))
+	}
+	return lines
+}
+
+func parseCompDoc(lines []string) compDoc {
+	doc := compDoc{params: map[string]string{}}
+
+	description := []string{}
+	for _, line := range lines {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "%%"))
+
+		if rest, ok := strings.CutPrefix(text, "@param "); ok {
+			name, paramDescription, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			doc.params[name] = strings.TrimSpace(paramDescription)
+			continue
+		}
+
+		description = append(description, text)
+	}
+
+	doc.description = strings.TrimSpace(strings.Join(description, "\n"))
+	return doc
+}
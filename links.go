@@ -0,0 +1,29 @@
+package compono
+
+import (
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/links"
+)
+
+// Links returns the outgoing links of source, including those of the
+// components it uses, in order, with their URLs as written and as resolved
+// by the link resolver. Broken and unsafe links are reported instead of
+// being turned into errors.
+func (c *compono) Links(source []byte, opts ...ConvertOption) ([]*links.Link, error) {
+	if len(source) == 0 {
+		return []*links.Link{}, nil
+	}
+
+	cfg := c.newConvertConfig(opts)
+	root, err := c.parseRoot(source)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.validator.Validate(root)
+	if err != nil {
+		return nil, NewComponoError(ErrInvalidAST, err.Error())
+	}
+
+	return errwrap.Links(root, cfg.wrapOptions()...), nil
+}
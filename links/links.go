@@ -0,0 +1,46 @@
+// Package links lets applications resolve the links of compono sources to
+// their own routing, such as [About](page:about-us) to /about-us, and
+// reports the links a page points to.
+package links
+
+import (
+	"github.com/umono-cms/compono/ast"
+)
+
+// Resolver resolves the URLs of links and of the URL parameters of builtin
+// components, as their authors wrote them.
+type Resolver interface {
+	Resolve(url string) Resolution
+}
+
+// ResolverFunc is a Resolver function.
+type ResolverFunc func(url string) Resolution
+
+func (f ResolverFunc) Resolve(url string) Resolution {
+	return f(url)
+}
+
+// Resolution is what a URL resolves to. An empty URL keeps the written
+// one. Rel and Target set the rel and target attributes of the link, such
+// as nofollow for external hosts. Broken marks an internal target that
+// does not exist, which is reported as an error.
+type Resolution struct {
+	URL    string
+	Rel    string
+	Target string
+	Broken bool
+}
+
+// Link is an outgoing link of a page. URL is the URL as written and Href
+// the URL it resolved to. Unsafe marks a URL the URL policy rejects. Layer
+// names the forked instance whose global component the link is in, if any.
+type Link struct {
+	URL    string    `json:"url"`
+	Href   string    `json:"href"`
+	Rel    string    `json:"rel,omitempty"`
+	Target string    `json:"target,omitempty"`
+	Broken bool      `json:"broken,omitempty"`
+	Unsafe bool      `json:"unsafe,omitempty"`
+	Range  ast.Range `json:"range"`
+	Layer  string    `json:"layer,omitempty"`
+}
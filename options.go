@@ -0,0 +1,123 @@
+package compono
+
+import (
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/links"
+	"github.com/umono-cms/compono/urlpolicy"
+)
+
+type ConvertOption func(*convertConfig)
+
+type convertConfig struct {
+	strict       bool
+	locale       string
+	warnings     bool
+	urlPolicy    *urlpolicy.Policy
+	linkResolver links.Resolver
+}
+
+// WithStrict overrides the instance's strict mode for a single conversion.
+// In strict mode a conversion fails with ErrWrapped instead of rendering
+// error placeholders, and nothing is written.
+func WithStrict(strict bool) ConvertOption {
+	return func(cfg *convertConfig) {
+		cfg.strict = strict
+	}
+}
+
+// WithLocale overrides the instance's locale of error titles and messages
+// for a single conversion.
+func WithLocale(locale string) ConvertOption {
+	return func(cfg *convertConfig) {
+		cfg.locale = locale
+	}
+}
+
+// WithWarnings overrides whether lint warnings are rendered after the
+// document for a single conversion.
+func WithWarnings(warnings bool) ConvertOption {
+	return func(cfg *convertConfig) {
+		cfg.warnings = warnings
+	}
+}
+
+// WithURLPolicy overrides the instance's URL policy for a single
+// conversion.
+func WithURLPolicy(policy *urlpolicy.Policy) ConvertOption {
+	return func(cfg *convertConfig) {
+		cfg.urlPolicy = policy
+	}
+}
+
+// WithLinkResolver overrides the instance's link resolver for a single
+// conversion.
+func WithLinkResolver(resolver links.Resolver) ConvertOption {
+	return func(cfg *convertConfig) {
+		cfg.linkResolver = resolver
+	}
+}
+
+func (c *compono) newConvertConfig(opts []ConvertOption) *convertConfig {
+	cfg := &convertConfig{
+		strict:       c.strict,
+		locale:       c.locale,
+		warnings:     c.warnings,
+		urlPolicy:    c.urlPolicy,
+		linkResolver: c.linkResolver,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// wrapOptions returns the options of the error wrapper for a conversion.
+func (cfg *convertConfig) wrapOptions() []errwrap.WrapOption {
+	return []errwrap.WrapOption{
+		errwrap.WithLocale(cfg.locale),
+		errwrap.WithURLPolicy(cfg.urlPolicy),
+		errwrap.WithLinkResolver(cfg.linkResolver),
+	}
+}
+
+type RegisterOption func(*registerConfig)
+
+type registerConfig struct {
+	validate  bool
+	namespace string
+}
+
+// WithValidation makes RegisterGlobalComponent reject a global component
+// whose content has errors, such as calls to unknown components, undefined
+// parameters or a cycle with the registered globals. The returned error
+// holds the diagnostics, whose source is the global component's name.
+func WithValidation(validate bool) RegisterOption {
+	return func(cfg *registerConfig) {
+		cfg.validate = validate
+	}
+}
+
+// WithNamespace registers global components under a namespace, such as
+// SHOP for SHOP.PRODUCT_CARD, so that themes and plugins do not collide.
+// ReplaceGlobalComponents then replaces only the components of the
+// namespace.
+func WithNamespace(namespace string) RegisterOption {
+	return func(cfg *registerConfig) {
+		cfg.namespace = namespace
+	}
+}
+
+func (cfg *registerConfig) qualify(name string) string {
+	if cfg.namespace == "" {
+		return name
+	}
+	return cfg.namespace + "." + name
+}
+
+func (c *compono) newRegisterConfig(opts []RegisterOption) *registerConfig {
+	cfg := &registerConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
@@ -23,6 +23,12 @@ type parser struct {
 
 func (p *parser) Parse(source []byte, root ast.Node) ast.Node {
 	p.logger.Enter(logger.Parser, "Parser started")
+	if root.Source() == nil {
+		root.SetSource(ast.NewSource("", source))
+		root.SetOffsets(0, len(source))
+	} else if start, end := root.Offsets(); start == 0 && end == 0 {
+		root.SetOffsets(0, len(source))
+	}
 	node := p.parse(source, root)
 	p.logger.Exit(logger.Parser, "Parser finished")
 	return node
@@ -91,11 +97,17 @@ func (p *parser) parse(source []byte, parentNode ast.Node) ast.Node {
 
 	children := []ast.Node{}
 
+	// Selectors work on the parent's raw bytes, so child offsets are relative
+	// to the parent and have to be shifted to stay absolute in the source.
+	base, _ := parentNode.Offsets()
+
 	for _, f := range found {
 		nodeForm := ast.DefaultEmptyNode()
 		nodeForm.SetRule(f.rule)
 		nodeForm.SetRaw(source[f.start:f.end])
 		nodeForm.SetParent(parentNode)
+		nodeForm.SetSource(parentNode.Source())
+		nodeForm.SetOffsets(base+f.start, base+f.end)
 		children = append(children, nodeForm)
 	}
 
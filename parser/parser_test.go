@@ -156,6 +156,33 @@ func (s *parserTestSuite) TestParse() {
 	}
 }
 
+func (s *parserTestSuite) TestParsePositions() {
+	root := DefaultParser(logger.NewLogger()).Parse([]byte("# Title\n\nHello **wörld** {{ NAME }}"), ast.DefaultRootNode())
+
+	start, end := root.Offsets()
+	assert.Equal(s.T(), 0, start)
+	assert.Equal(s.T(), 36, end)
+	require.NotNil(s.T(), root.Source())
+
+	strong := ast.FilterNodesInTree(root, func(n ast.Node) bool {
+		return ast.IsRuleName(n, "strong-content")
+	})
+	require.Len(s.T(), strong, 1)
+
+	start, end = strong[0].Offsets()
+	assert.Equal(s.T(), "wörld", string(root.Source().Content()[start:end]))
+	assert.Equal(s.T(), ast.Position{Offset: 17, Line: 3, Column: 9}, strong[0].Range().Start)
+	assert.Equal(s.T(), ast.Position{Offset: 23, Line: 3, Column: 14}, strong[0].Range().End)
+
+	compCallName := ast.FilterNodesInTree(root, func(n ast.Node) bool {
+		return ast.IsRuleName(n, "comp-call-name")
+	})
+	require.Len(s.T(), compCallName, 1)
+
+	start, end = compCallName[0].Offsets()
+	assert.Equal(s.T(), string(compCallName[0].Raw()), string(root.Source().Content()[start:end]))
+}
+
 func TestParserTestSuite(t *testing.T) {
 	suite.Run(t, new(parserTestSuite))
 }
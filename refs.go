@@ -0,0 +1,117 @@
+package compono
+
+import (
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+)
+
+type compRef struct {
+	name string
+	node ast.Node
+	// nameNode is the node whose raw holds the name.
+	nameNode ast.Node
+}
+
+// compRefNames returns the component names used in node's tree: called,
+// passed as component arguments, set as component parameter defaults or
+// declared as layouts.
+func compRefNames(node ast.Node) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, ref := range compRefs(node) {
+		if seen[ref.name] {
+			continue
+		}
+		seen[ref.name] = true
+		names = append(names, ref.name)
+	}
+	return names
+}
+
+// compRefs returns the component references in node's tree with the nodes
+// they are made from, which determine the namespace they resolve in.
+func compRefs(node ast.Node) []compRef {
+	result := []compRef{}
+	add := func(ref ast.Node, nameNode ast.Node) {
+		name := strings.TrimSpace(string(nameNode.Raw()))
+		if name == "" {
+			return
+		}
+		result = append(result, compRef{name: name, node: ref, nameNode: nameNode})
+	}
+
+	refs := ast.FilterNodesInTree(node, func(n ast.Node) bool {
+		return ast.IsRuleNameOneOf(n, []string{"block-comp-call", "inline-comp-call", "comp-call-arg", "comp-param", "layout-decl"})
+	})
+
+	for _, ref := range refs {
+		switch ref.Rule().Name() {
+		case "block-comp-call", "inline-comp-call":
+			compCallName := ast.FindNodeByRuleName(ref.Children(), "comp-call-name")
+			if compCallName != nil {
+				add(ref, compCallName)
+			}
+		case "comp-call-arg":
+			if ast.GetTypeFromCompCallArg(ref) == "comp" {
+				if value := compCallArgValue(ref); value != nil {
+					add(ref, value)
+				}
+			}
+		case "comp-param":
+			if ast.GetTypeFromCompParam(ref) == "comp" {
+				if value := compParamDefValNode(ref); value != nil {
+					add(ref, value)
+				}
+			}
+		case "layout-decl":
+			if layoutName := ast.FindNodeByRuleName(ref.Children(), "layout-name"); layoutName != nil {
+				add(ref, layoutName)
+			}
+		}
+	}
+
+	return result
+}
+
+// localCompDefSource returns the node whose local components node can
+// call: its global component definition, or root.
+func localCompDefSource(root ast.Node, node ast.Node) ast.Node {
+	globalCompDef := ast.FindNode(ast.GetAncestors(node), func(anc ast.Node) bool {
+		return ast.IsRuleName(anc, "global-comp-def")
+	})
+	if globalCompDef != nil {
+		return globalCompDef
+	}
+	return root
+}
+
+// compCallArgValue returns the value node of a component call argument,
+// the node GetArgValueFromCompCallArg reads.
+func compCallArgValue(compCallArg ast.Node) ast.Node {
+	compCallArgType := ast.FindNodeByRuleName(compCallArg.Children(), "comp-call-arg-type")
+	if compCallArgType == nil || len(compCallArgType.Children()) == 0 {
+		return nil
+	}
+	compCallXArg := compCallArgType.Children()[0]
+	if len(compCallXArg.Children()) == 0 {
+		return nil
+	}
+	return compCallXArg.Children()[0]
+}
+
+func compParamDefValNode(compParam ast.Node) ast.Node {
+	compParamType := ast.FindNodeByRuleName(compParam.Children(), "comp-param-type")
+	if compParamType == nil || len(compParamType.Children()) == 0 {
+		return nil
+	}
+	return ast.FindNodeByRuleName(compParamType.Children()[0].Children(), "comp-param-defa-value")
+}
+
+func compParamDefVal(compParam ast.Node) string {
+	value := compParamDefValNode(compParam)
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(string(value.Raw()))
+}
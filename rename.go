@@ -0,0 +1,166 @@
+package compono
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+)
+
+// nameEdit replaces the component name at start in a source.
+type nameEdit struct {
+	start int
+	end   int
+	name  string
+}
+
+// Rename returns source with the component oldName renamed to newName: its
+// calls, the component arguments and parameter defaults that pass it, the
+// layout declarations that name it and its local definition, if source has
+// one. All other bytes are kept.
+func (c *compono) Rename(source []byte, oldName, newName string) ([]byte, error) {
+	if err := validateGlobalName(newName); err != nil {
+		return nil, err
+	}
+	if len(source) == 0 {
+		return source, nil
+	}
+
+	root := c.parser.Parse(source, ast.DefaultRootNode())
+
+	edits := []nameEdit{}
+	for _, ref := range compRefs(root) {
+		if ref.name == oldName {
+			edits = appendNameEdit(edits, ref.nameNode, newName)
+		}
+	}
+
+	if localCompDef := ast.FindLocalCompDef(root, oldName); localCompDef != nil {
+		if strings.Contains(newName, ".") {
+			return nil, NewComponoError(ErrInvalidGlobalName, fmt.Sprintf("cannot rename local component %q to %q: local components have no namespace", oldName, newName))
+		}
+		localCompDefHead := ast.FindNodeByRuleName(localCompDef.Children(), "local-comp-def-head")
+		if localCompName := ast.FindNodeByRuleName(localCompDefHead.Children(), "local-comp-name"); localCompName != nil {
+			edits = appendNameEdit(edits, localCompName, newName)
+		}
+	}
+
+	return applyNameEdits(source, edits), nil
+}
+
+// RenameGlobalComponent renames the registered global component oldName to
+// newName and rewrites the registered global components that use it, like
+// Rename does, in a single step. A call is left unqualified when the new
+// name resolves from the caller without its namespace. Components of the
+// store and of parent layers are not changed.
+func (c *compono) RenameGlobalComponent(oldName, newName string) error {
+	if err := validateGlobalName(newName); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.getGlobalCompDefByName(oldName) == nil {
+		return NewComponoError(ErrGlobalNotExist, fmt.Sprintf("cannot rename global component %q: does not exist", oldName))
+	}
+
+	own := c.globalWrapper.Children()
+	cloned := make([]ast.Node, len(own))
+	for i, globalCompDef := range own {
+		cloned[i] = c.cloneNode(globalCompDef)
+	}
+
+	root, err := c.globalsRoot(c.withParentGlobals(cloned))
+	if err != nil {
+		return err
+	}
+	if ast.FindGlobalCompDef(root, newName) != nil {
+		return NewComponoError(ErrGlobalAlreadyRegistered, fmt.Sprintf("cannot rename global component %q to %q: already registered", oldName, newName))
+	}
+
+	renamed := make([]ast.Node, len(own))
+	for i, globalCompDef := range cloned {
+		name := globalCompName(globalCompDef)
+
+		edits := []nameEdit{}
+		for _, ref := range compRefs(globalCompDef) {
+			if !ast.IsRuleName(ref.node, "layout-decl") && ast.FindLocalCompDef(globalCompDef, ref.name) != nil {
+				continue
+			}
+			resolved := ast.ResolveGlobalCompDef(root, globalCompDef, ref.name)
+			if resolved == nil || globalCompName(resolved) != oldName {
+				continue
+			}
+			edits = appendNameEdit(edits, ref.nameNode, referenceName(root, globalCompDef, ref.name, oldName, newName))
+		}
+
+		if name == oldName {
+			name = newName
+		} else if len(edits) == 0 {
+			renamed[i] = own[i]
+			continue
+		}
+
+		renamed[i] = c.parseGlobal(name, applyNameEdits(globalCompDef.Source().Content(), edits))
+		renamed[i].SetParent(c.globalWrapper)
+	}
+
+	c.globalWrapper.SetChildren(renamed)
+	return nil
+}
+
+// referenceName returns the name scope refers to newName with, in place of
+// written, which refers to oldName. Written qualified names stay
+// qualified, unqualified ones stay so when that resolves to newName.
+func referenceName(root ast.Node, scope ast.Node, written, oldName, newName string) string {
+	if strings.Contains(written, ".") {
+		return newName
+	}
+
+	unqualified := newName[strings.LastIndex(newName, ".")+1:]
+	if ast.FindLocalCompDef(scope, unqualified) != nil {
+		return newName
+	}
+	for _, candidate := range ast.GlobalCompNameCandidates(scope, unqualified) {
+		if candidate == newName {
+			return unqualified
+		}
+		if candidate != oldName && ast.FindGlobalCompDef(root, candidate) != nil {
+			break
+		}
+	}
+	return newName
+}
+
+func appendNameEdit(edits []nameEdit, nameNode ast.Node, name string) []nameEdit {
+	start, _ := nameNode.Offsets()
+	written := bytes.TrimSpace(nameNode.Raw())
+	offset := bytes.Index(nameNode.Raw(), written)
+	return append(edits, nameEdit{
+		start: start + offset,
+		end:   start + offset + len(written),
+		name:  name,
+	})
+}
+
+func applyNameEdits(source []byte, edits []nameEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var out bytes.Buffer
+	last := 0
+	for _, edit := range edits {
+		if edit.start < last || edit.end > len(source) {
+			continue
+		}
+		out.Write(source[last:edit.start])
+		out.WriteString(edit.name)
+		last = edit.end
+	}
+	out.Write(source[last:])
+	return out.Bytes()
+}
@@ -3,21 +3,21 @@ package html
 import "github.com/umono-cms/compono/ast"
 
 type br struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newBr(rend *renderer) renderableNode {
+func newBr(rend *Renderer) RenderableNode {
 	return &br{
 		renderer: rend,
 	}
 }
 
-func (b *br) New() renderableNode {
+func (b *br) New() RenderableNode {
 	return newBr(b.renderer)
 }
 
-func (_ *br) Condition(_ renderableNode, node ast.Node) bool {
+func (_ *br) Condition(_ RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "soft-break")
 }
 
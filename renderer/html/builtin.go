@@ -10,14 +10,14 @@ import (
 type builtinComponent interface {
 	New() builtinComponent
 	Name() string
-	Render(invoker renderableNode, node ast.Node) string
+	Render(state *renderState, invoker RenderableNode, node ast.Node) string
 }
 
 type link struct {
-	renderer *renderer
+	renderer *Renderer
 }
 
-func newLink(rend *renderer) builtinComponent {
+func newLink(rend *Renderer) builtinComponent {
 	return &link{
 		renderer: rend,
 	}
@@ -31,13 +31,43 @@ func (_ *link) Name() string {
 	return "LINK"
 }
 
-func (_ *link) Render(invoker renderableNode, node ast.Node) string {
-	newTabStr := ""
+func (_ *link) Render(_ *renderState, invoker RenderableNode, node ast.Node) string {
 	newTab, ok := getBoolArgValue(node, "new-tab")
-	if ok && newTab {
-		newTabStr = ` target="_blank" rel="noopener noreferrer"`
+	attrs := LinkAttrs(node, ok && newTab)
+	return "<a href=\"" + getArgValueWithDefa(node, "url", "url") + "\"" + attrs + ">" + getArgValueWithDefa(node, "text", "") + "</a>"
+}
+
+type content struct {
+	renderer *Renderer
+}
+
+func newContent(rend *Renderer) builtinComponent {
+	return &content{
+		renderer: rend,
 	}
-	return "<a href=\"" + getArgValueWithDefa(node, "url", "url") + "\"" + newTabStr + ">" + getArgValueWithDefa(node, "text", "") + "</a>"
+}
+
+func (c *content) New() builtinComponent {
+	return newContent(c.renderer)
+}
+
+func (_ *content) Name() string {
+	return "CONTENT"
+}
+
+// Render renders the innermost content waiting for its layout. It is taken
+// off the stack meanwhile, so that a CONTENT call of a nested layout's
+// content renders the next one.
+func (_ *content) Render(state *renderState, invoker RenderableNode, _ ast.Node) string {
+	if len(state.contents) == 0 {
+		return ""
+	}
+
+	top := state.contents[len(state.contents)-1]
+	state.contents = state.contents[:len(state.contents)-1]
+	rendered := state.renderChildren(invoker, top.Children())
+	state.contents = append(state.contents, top)
+	return rendered
 }
 
 func getArgValueWithDefa(compCall ast.Node, name string, defa string) string {
@@ -49,32 +79,43 @@ func getArgValueWithDefa(compCall ast.Node, name string, defa string) string {
 }
 
 func getArgValue(compCall ast.Node, name string) (string, bool) {
+	value, ok := argValue(compCall, name)
+	return html.EscapeString(value), ok
+}
+
+func argValue(compCall ast.Node, name string) (string, bool) {
+	value := argValueNode(compCall, name)
+	if value == nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(value.Raw())), true
+}
+
+// argValueNode returns the value node of the literal string, number or bool
+// argument name of compCall.
+func argValueNode(compCall ast.Node, name string) ast.Node {
 	compCallArgs := ast.FindNodeByRuleName(compCall.Children(), "comp-call-args")
 	if compCallArgs == nil {
-		return "", false
+		return nil
 	}
 	compCallArg := ast.FindNode(compCallArgs.Children(), func(node ast.Node) bool {
 		argName := ast.FindNodeByRuleName(node.Children(), "comp-call-arg-name")
 		return strings.TrimSpace(string(argName.Raw())) == name
 	})
 	if compCallArg == nil {
-		return "", false
+		return nil
 	}
 	compCallArgType := ast.FindNodeByRuleName(compCallArg.Children(), "comp-call-arg-type")
 	if compCallArgType == nil {
-		return "", false
+		return nil
 	}
 	typedArg := ast.FindNode(compCallArgType.Children(), func(node ast.Node) bool {
 		return ast.IsRuleNameOneOf(node, []string{"comp-call-string-arg", "comp-call-number-arg", "comp-call-bool-arg"})
 	})
 	if typedArg == nil {
-		return "", false
-	}
-	argValue := ast.FindNodeByRuleName(typedArg.Children(), "comp-call-arg-value")
-	if argValue == nil {
-		return "", false
+		return nil
 	}
-	return html.EscapeString(strings.TrimSpace(string(argValue.Raw()))), true
+	return ast.FindNodeByRuleName(typedArg.Children(), "comp-call-arg-value")
 }
 
 func getBoolArgValue(compCall ast.Node, name string) (bool, bool) {
@@ -8,21 +8,21 @@ import (
 )
 
 type codeBlock struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newCodeBlock(rend *renderer) renderableNode {
+func newCodeBlock(rend *Renderer) RenderableNode {
 	return &codeBlock{
 		renderer: rend,
 	}
 }
 
-func (cb *codeBlock) New() renderableNode {
+func (cb *codeBlock) New() RenderableNode {
 	return newCodeBlock(cb.renderer)
 }
 
-func (_ *codeBlock) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *codeBlock) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "code-block")
 }
 
@@ -35,74 +35,74 @@ func (cb *codeBlock) Render() string {
 			langClass = "language-" + lang
 		}
 	}
-	return `<pre><code class="` + langClass + `">` + cb.renderer.renderChildren(cb, cb.Node().Children()) + `</code></pre>`
+	return `<pre><code class="` + langClass + `">` + cb.state.renderChildren(cb, cb.Node().Children()) + `</code></pre>`
 }
 
 type codeBlockContent struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newCodeBlockContent(rend *renderer) renderableNode {
+func newCodeBlockContent(rend *Renderer) RenderableNode {
 	return &codeBlockContent{
 		renderer: rend,
 	}
 }
 
-func (cbc *codeBlockContent) New() renderableNode {
+func (cbc *codeBlockContent) New() RenderableNode {
 	return newCodeBlockContent(cbc.renderer)
 }
 
-func (_ *codeBlockContent) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *codeBlockContent) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "code-block-content")
 }
 
 func (cbc *codeBlockContent) Render() string {
-	return cbc.renderer.renderChildren(cbc, cbc.Node().Children())
+	return cbc.state.renderChildren(cbc, cbc.Node().Children())
 }
 
 type inlineCode struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newInlineCode(rend *renderer) renderableNode {
+func newInlineCode(rend *Renderer) RenderableNode {
 	return &inlineCode{
 		renderer: rend,
 	}
 }
 
-func (ic *inlineCode) New() renderableNode {
+func (ic *inlineCode) New() RenderableNode {
 	return newInlineCode(ic.renderer)
 }
 
-func (_ *inlineCode) Condition(_ renderableNode, node ast.Node) bool {
+func (_ *inlineCode) Condition(_ RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "inline-code")
 }
 
 func (ic *inlineCode) Render() string {
-	return `<code style="white-space: pre">` + ic.renderer.renderChildren(ic, ic.Node().Children()) + "</code>"
+	return `<code style="white-space: pre">` + ic.state.renderChildren(ic, ic.Node().Children()) + "</code>"
 }
 
 type inlineCodeContent struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newInlineCodeContent(rend *renderer) renderableNode {
+func newInlineCodeContent(rend *Renderer) RenderableNode {
 	return &inlineCodeContent{
 		renderer: rend,
 	}
 }
 
-func (icc *inlineCodeContent) New() renderableNode {
+func (icc *inlineCodeContent) New() RenderableNode {
 	return newInlineCodeContent(icc.renderer)
 }
 
-func (_ *inlineCodeContent) Condition(_ renderableNode, node ast.Node) bool {
+func (_ *inlineCodeContent) Condition(_ RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "inline-code-content")
 }
 
 func (icc *inlineCodeContent) Render() string {
-	return icc.renderer.renderChildren(icc, icc.Node().Children())
+	return icc.state.renderChildren(icc, icc.Node().Children())
 }
@@ -7,21 +7,21 @@ import (
 )
 
 type compCall struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newCompCall(rend *renderer) renderableNode {
+func newCompCall(rend *Renderer) RenderableNode {
 	return &compCall{
 		renderer: rend,
 	}
 }
 
-func (cc *compCall) New() renderableNode {
+func (cc *compCall) New() RenderableNode {
 	return newCompCall(cc.renderer)
 }
 
-func (_ *compCall) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *compCall) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleNameOneOf(node, []string{"block-comp-call", "inline-comp-call"})
 }
 
@@ -36,7 +36,7 @@ func (cc *compCall) Render() string {
 		return ast.IsRuleName(anc, "global-comp-def")
 	})
 
-	localCompDefSrc := cc.renderer.root
+	localCompDefSrc := cc.state.root
 	if globalCompDefAnc != nil {
 		localCompDefSrc = globalCompDefAnc
 	}
@@ -50,10 +50,10 @@ func (cc *compCall) Render() string {
 		if inlineCompCall {
 			return cc.renderInlineCompCall(strings.TrimSpace(string(compCallName.Raw())), localCompDefContent)
 		}
-		return cc.renderer.renderChildren(cc, localCompDefContent.Children())
+		return cc.state.renderChildren(cc, localCompDefContent.Children())
 	}
 
-	globalCompDef := cc.renderer.findGlobalCompDef(string(compCallName.Raw()))
+	globalCompDef := cc.state.findGlobalCompDef(cc.Node(), string(compCallName.Raw()))
 	if globalCompDef != nil {
 		globalCompDefContent := ast.FindNodeByRuleName(globalCompDef.Children(), "global-comp-def-content")
 		if globalCompDefContent == nil {
@@ -62,12 +62,12 @@ func (cc *compCall) Render() string {
 		if inlineCompCall {
 			return cc.renderInlineCompCall(strings.TrimSpace(string(compCallName.Raw())), globalCompDefContent)
 		}
-		return cc.renderer.renderChildren(cc, globalCompDefContent.Children())
+		return cc.state.renderContent(cc, globalCompDefContent)
 	}
 
-	builtinComp := cc.renderer.findBuiltinComp(string(compCallName.Raw()))
+	builtinComp := cc.state.findBuiltinComp(string(compCallName.Raw()))
 	if builtinComp != nil {
-		return builtinComp.Render(cc.Invoker(), cc.Node())
+		return builtinComp.Render(cc.state, cc.Invoker(), cc.Node())
 	}
 
 	return ""
@@ -79,6 +79,9 @@ func (cc *compCall) renderInlineCompCall(compName string, compDefContent ast.Nod
 		return ""
 	}
 	p := ast.FindNodeByRuleName(compDefContent.Children(), "p")
+	if p == nil {
+		return ""
+	}
 	pContent := ast.FindNodeByRuleName(p.Children(), "p-content")
-	return cc.renderer.renderChildren(cc, pContent.Children())
+	return cc.state.renderChildren(cc, pContent.Children())
 }
@@ -7,21 +7,21 @@ import (
 )
 
 type nonVoidElement struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newNonVoidElement(rend *renderer) renderableNode {
+func newNonVoidElement(rend *Renderer) RenderableNode {
 	return &nonVoidElement{
 		renderer: rend,
 	}
 }
 
-func (nve *nonVoidElement) New() renderableNode {
+func (nve *nonVoidElement) New() RenderableNode {
 	return newNonVoidElement(nve.renderer)
 }
 
-func (_ *nonVoidElement) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *nonVoidElement) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleNameOneOf(node, []string{
 		"h1",
 		"h2",
@@ -36,25 +36,25 @@ func (_ *nonVoidElement) Condition(invoker renderableNode, node ast.Node) bool {
 }
 
 func (nve *nonVoidElement) Render() string {
-	return nve.renderer.renderChildren(nve, nve.Node().Children())
+	return nve.state.renderChildren(nve, nve.Node().Children())
 }
 
 type nonVoidElementContent struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newNonVoidElementContent(rend *renderer) renderableNode {
+func newNonVoidElementContent(rend *Renderer) RenderableNode {
 	return &nonVoidElementContent{
 		renderer: rend,
 	}
 }
 
-func (nvec *nonVoidElementContent) New() renderableNode {
+func (nvec *nonVoidElementContent) New() RenderableNode {
 	return newNonVoidElementContent(nvec.renderer)
 }
 
-func (_ *nonVoidElementContent) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *nonVoidElementContent) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleNameOneOf(node, []string{
 		"h1-content",
 		"h2-content",
@@ -85,10 +85,11 @@ func (nvec *nonVoidElementContent) Render() string {
 	tag := name[:idx]
 
 	if tag == "p" {
-		rendered := nvec.renderer.renderChildren(nvec, nvec.Node().Children())
+		blockErrCount := nvec.state.blockErrCount
+		rendered := nvec.state.renderChildren(nvec, nvec.Node().Children())
 		if ast.FindNodeByRuleName(nvec.Node().Children(), "soft-break") != nil &&
-			strings.Contains(rendered, "<compono-error-block>") {
-			return splitParagraphByBreakWithBlockErr(rendered)
+			nvec.state.blockErrCount > blockErrCount {
+			return nvec.state.splitParagraphByBreakWithBlockErr(rendered)
 		}
 
 		if standaloneCompParamRefInParagraph(nvec.Node()) != nil {
@@ -100,7 +101,7 @@ func (nvec *nonVoidElementContent) Render() string {
 		return "<p>" + rendered + "</p>"
 	}
 
-	return "<" + tag + ">" + nvec.renderer.renderChildren(nvec, nvec.Node().Children()) + "</" + tag + ">"
+	return "<" + tag + ">" + nvec.state.renderChildren(nvec, nvec.Node().Children()) + "</" + tag + ">"
 }
 
 func renderParagraphWithBlockErrors(nvec *nonVoidElementContent) string {
@@ -117,7 +118,7 @@ func renderParagraphWithBlockErrors(nvec *nonVoidElementContent) string {
 		if len(chunk) == 0 {
 			return
 		}
-		content := nvec.renderer.renderChildren(nvec, chunk)
+		content := nvec.state.renderChildren(nvec, chunk)
 		if content != "" {
 			result += "<p>" + content + "</p>"
 		}
@@ -127,7 +128,7 @@ func renderParagraphWithBlockErrors(nvec *nonVoidElementContent) string {
 	for _, child := range children {
 		if ast.IsRuleName(child, "block-error") {
 			flushChunk()
-			result += nvec.renderer.renderChildren(nvec, []ast.Node{child})
+			result += nvec.state.renderChildren(nvec, []ast.Node{child})
 			continue
 		}
 
@@ -143,7 +144,7 @@ func renderParagraphWithBlockErrors(nvec *nonVoidElementContent) string {
 	return result
 }
 
-func splitParagraphByBreakWithBlockErr(rendered string) string {
+func (s *renderState) splitParagraphByBreakWithBlockErr(rendered string) string {
 	parts := strings.Split(rendered, "<br>")
 	result := ""
 	for _, part := range parts {
@@ -151,7 +152,7 @@ func splitParagraphByBreakWithBlockErr(rendered string) string {
 		if part == "" {
 			continue
 		}
-		if strings.HasPrefix(part, "<compono-error-block>") {
+		if s.isBlockErr(part) {
 			result += part
 			continue
 		}
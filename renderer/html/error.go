@@ -1,61 +1,139 @@
 package html
 
 import (
-	"regexp"
+	"html"
 	"strings"
 
 	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/errwrap"
 )
 
+// ErrorRenderer renders the block-error and inline-error nodes placed by the
+// error wrapper.
+type ErrorRenderer interface {
+	RenderError(d *errwrap.Diagnostic) string
+}
+
+type ErrorRendererFunc func(d *errwrap.Diagnostic) string
+
+func (f ErrorRendererFunc) RenderError(d *errwrap.Diagnostic) string {
+	return f(d)
+}
+
+type ErrorMode int
+
+const (
+	// <compono-error-block>, <compono-error-inline> and <compono-warning-block> web components
+	ErrorModeWebComponent ErrorMode = iota
+	// <div class="compono-error"> for block errors, <span class="compono-error"> for inline errors,
+	// <div class="compono-warning"> for warnings
+	ErrorModeDiv
+	// HTML comments only, for production
+	ErrorModeComment
+	// No output at all
+	ErrorModeSuppress
+)
+
+func NewErrorRenderer(mode ErrorMode) ErrorRenderer {
+	switch mode {
+	case ErrorModeDiv:
+		return ErrorRendererFunc(divError)
+	case ErrorModeComment:
+		return ErrorRendererFunc(commentError)
+	case ErrorModeSuppress:
+		return ErrorRendererFunc(func(_ *errwrap.Diagnostic) string { return "" })
+	default:
+		return ErrorRendererFunc(webComponentError)
+	}
+}
+
 type err struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newErr(rend *renderer) renderableNode {
+func newErr(rend *Renderer) RenderableNode {
 	return &err{
 		renderer: rend,
 	}
 }
 
-func (e *err) New() renderableNode {
+func (e *err) New() RenderableNode {
 	return newErr(e.renderer)
 }
 
-func (_ *err) Condition(_ renderableNode, node ast.Node) bool {
-	return ast.IsRuleNameOneOf(node, []string{"block-error", "inline-error"})
+func (_ *err) Condition(_ RenderableNode, node ast.Node) bool {
+	return ast.IsRuleNameOneOf(node, []string{"block-error", "inline-error", "block-warning"})
 }
 
 func (e *err) Render() string {
-	title := ast.FindNodeByRuleName(e.Node().Children(), "error-title")
-	message := ast.FindNodeByRuleName(e.Node().Children(), "error-message")
-
-	titleStr := strings.TrimSpace(string(title.Raw()))
-	messageStr := strings.TrimSpace(string(message.Raw()))
+	d := errwrap.NewDiagnostic(e.Node())
+	rendered := e.renderer.errorRenderer.RenderError(d)
+	if d.Block {
+		e.state.markBlockErr(rendered)
+	}
+	return rendered
+}
 
-	// TODO: This is an ugly hack
-	re := regexp.MustCompile(`\*\*([^*]+)\*\*`)
-	messageStr = re.ReplaceAllString(messageStr, "<strong>$1</strong>")
+func webComponentError(d *errwrap.Diagnostic) string {
+	if d.Severity == errwrap.SeverityWarning {
+		return `<compono-warning-block><div slot="title">` +
+			html.EscapeString(d.Title) +
+			`</div><div slot="description">` +
+			messageHTML(d.Parts) +
+			`</div></compono-warning-block>`
+	}
 
-	if ast.IsRuleName(e.Node(), "block-error") {
-		return e.blockError(titleStr, messageStr)
+	if d.Block {
+		return `<compono-error-block><div slot="title">` +
+			html.EscapeString(d.Title) +
+			`</div><div slot="description">` +
+			messageHTML(d.Parts) +
+			`</div></compono-error-block>`
 	}
 
-	return e.inlineError(titleStr, messageStr)
+	return `<compono-error-inline><span slot="title">` +
+		html.EscapeString(d.Title) +
+		`</span><span slot="description">` +
+		messageHTML(d.Parts) +
+		`</span></compono-error-inline>`
 }
 
-func (e *err) blockError(title, msg string) string {
-	return `<compono-error-block><div slot="title">` +
-		title +
-		`</div><div slot="description">` +
-		msg +
-		`</div></compono-error-block>`
+func divError(d *errwrap.Diagnostic) string {
+	tag := "span"
+	if d.Block {
+		tag = "div"
+	}
+	class := "compono-error"
+	if d.Severity == errwrap.SeverityWarning {
+		class = "compono-warning"
+	}
+	return `<` + tag + ` class="` + class + `" data-code="` + html.EscapeString(string(d.Code)) + `">` +
+		`<strong class="compono-error-title">` + html.EscapeString(d.Title) + `</strong> ` +
+		messageHTML(d.Parts) +
+		`</` + tag + `>`
 }
 
-func (e *err) inlineError(title, msg string) string {
-	return `<compono-error-inline><span slot="title">` +
-		title +
-		`</span><span slot="description">` +
-		msg +
-		`</span></compono-error-inline>`
+func commentError(d *errwrap.Diagnostic) string {
+	content := "compono " + string(d.Code) + ": " + d.Title + ": " + d.Message
+	if d.Severity == errwrap.SeverityWarning {
+		content = "compono warning " + string(d.Code) + ": " + d.Title + ": " + d.Message
+	}
+	// "--" must not appear inside an HTML comment
+	for strings.Contains(content, "--") {
+		content = strings.ReplaceAll(content, "--", "- -")
+	}
+	return "<!-- " + content + " -->"
+}
+
+func messageHTML(parts errwrap.Message) string {
+	var b strings.Builder
+	for _, part := range parts {
+		if part.Kind == errwrap.TextPart {
+			b.WriteString(html.EscapeString(part.Text))
+			continue
+		}
+		b.WriteString("<strong>" + html.EscapeString(part.Text) + "</strong>")
+	}
+	return b.String()
 }
@@ -4,7 +4,7 @@ import (
 	"github.com/umono-cms/compono/ast"
 )
 
-func getAncestorsByInvoker(rn renderableNode) []ast.Node {
+func getAncestorsByInvoker(rn RenderableNode) []ast.Node {
 	invoker := rn.Invoker()
 	if invoker == nil {
 		return []ast.Node{}
@@ -2,27 +2,28 @@ package html
 
 import (
 	"html"
+	"slices"
 	"strings"
 
 	"github.com/umono-cms/compono/ast"
 )
 
 type linkElement struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newLinkElement(rend *renderer) renderableNode {
+func newLinkElement(rend *Renderer) RenderableNode {
 	return &linkElement{
 		renderer: rend,
 	}
 }
 
-func (l *linkElement) New() renderableNode {
+func (l *linkElement) New() RenderableNode {
 	return newLinkElement(l.renderer)
 }
 
-func (_ *linkElement) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *linkElement) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "link")
 }
 
@@ -34,32 +35,80 @@ func (l *linkElement) Render() string {
 	url := ""
 
 	if linkText != nil {
-		text = l.renderer.renderChildren(l, linkText.Children())
+		text = l.state.renderChildren(l, linkText.Children())
 	}
 
 	if linkURL != nil {
 		url = html.EscapeString(strings.TrimSpace(string(linkURL.Raw())))
 	}
 
-	return `<a href="` + url + `">` + text + `</a>`
+	return `<a href="` + url + `"` + LinkAttrs(l.Node(), false) + `>` + text + `</a>`
+}
+
+// LinkRelTarget returns the rel and target the link resolver set for the
+// link or LINK call node, "" for those it did not set.
+func LinkRelTarget(node ast.Node) (rel string, target string) {
+	linkURL := argValueNode(node, "url")
+	if ast.IsRuleName(node, "link") {
+		linkURL = ast.FindNodeByRuleName(node.Children(), "link-url")
+	}
+	if linkURL == nil {
+		return "", ""
+	}
+
+	if linkRel := ast.FindNodeByRuleName(linkURL.Children(), "link-rel"); linkRel != nil {
+		rel = strings.Join(strings.Fields(string(linkRel.Raw())), " ")
+	}
+	if linkTarget := ast.FindNodeByRuleName(linkURL.Children(), "link-target"); linkTarget != nil {
+		target = strings.TrimSpace(string(linkTarget.Raw()))
+	}
+	return rel, target
+}
+
+// LinkAttrs returns the target and rel attributes of the link or LINK call
+// node, each with a leading space, as the link resolver set them. Links
+// that open in a new tab get noopener and noreferrer.
+func LinkAttrs(node ast.Node, newTab bool) string {
+	relValue, target := LinkRelTarget(node)
+	rel := strings.Fields(relValue)
+
+	if newTab {
+		target = "_blank"
+	}
+	if target == "_blank" {
+		for _, value := range []string{"noopener", "noreferrer"} {
+			if !slices.Contains(rel, value) {
+				rel = append(rel, value)
+			}
+		}
+	}
+
+	attrs := ""
+	if target != "" {
+		attrs += ` target="` + html.EscapeString(target) + `"`
+	}
+	if len(rel) > 0 {
+		attrs += ` rel="` + html.EscapeString(strings.Join(rel, " ")) + `"`
+	}
+	return attrs
 }
 
 type linkTextElement struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newLinkTextElement(rend *renderer) renderableNode {
+func newLinkTextElement(rend *Renderer) RenderableNode {
 	return &linkTextElement{
 		renderer: rend,
 	}
 }
 
-func (lt *linkTextElement) New() renderableNode {
+func (lt *linkTextElement) New() RenderableNode {
 	return newLinkTextElement(lt.renderer)
 }
 
-func (_ *linkTextElement) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *linkTextElement) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "link-text")
 }
 
@@ -68,21 +117,21 @@ func (lt *linkTextElement) Render() string {
 }
 
 type linkURLElement struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newLinkURLElement(rend *renderer) renderableNode {
+func newLinkURLElement(rend *Renderer) RenderableNode {
 	return &linkURLElement{
 		renderer: rend,
 	}
 }
 
-func (lu *linkURLElement) New() renderableNode {
+func (lu *linkURLElement) New() RenderableNode {
 	return newLinkURLElement(lu.renderer)
 }
 
-func (_ *linkURLElement) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *linkURLElement) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "link-url")
 }
 
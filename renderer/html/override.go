@@ -0,0 +1,135 @@
+package html
+
+import (
+	"github.com/umono-cms/compono/ast"
+)
+
+// NodeRenderer renders the nodes of a rule in place of the renderer's own
+// rendering. It is registered with Renderer.Override.
+type NodeRenderer interface {
+	RenderNode(ctx *Context, node ast.Node) string
+}
+
+type NodeRendererFunc func(ctx *Context, node ast.Node) string
+
+func (f NodeRendererFunc) RenderNode(ctx *Context, node ast.Node) string {
+	return f(ctx, node)
+}
+
+// Context lets a NodeRenderer call back into the renderer for the node it
+// renders.
+type Context struct {
+	renderer *Renderer
+	state    *renderState
+	self     RenderableNode
+	// fallback renders a builtin component call without its override.
+	fallback func() string
+}
+
+// Invoker returns the renderable that rendered the parent of the node, or
+// nil for the root.
+func (ctx *Context) Invoker() RenderableNode {
+	return ctx.self.Invoker()
+}
+
+// RenderChildren renders nodes, usually the children of the node, with the
+// overrides applied.
+func (ctx *Context) RenderChildren(nodes []ast.Node) string {
+	return ctx.state.renderChildren(ctx.self, nodes)
+}
+
+// RenderDefault renders the node as it would be rendered without the
+// override.
+func (ctx *Context) RenderDefault() string {
+	if ctx.fallback != nil {
+		return ctx.fallback()
+	}
+
+	node := ctx.self.Node()
+	rn := ctx.renderer.findDefaultRenderable(ctx.self.Invoker(), node)
+	if rn == nil {
+		return ""
+	}
+	return renderNode(rn, ctx.state, ctx.self.Invoker(), node)
+}
+
+// Override renders the nodes of the rule ruleName with nodeRenderer,
+// taking precedence over the default rendering and earlier overrides of
+// the rule. Rule names are those of the AST, e.g. "h2-content" for the
+// content of a level 2 heading.
+func (r *Renderer) Override(ruleName string, nodeRenderer NodeRenderer) {
+	r.overrides[ruleName] = nodeRenderer
+}
+
+// OverrideBuiltin renders the calls of the builtin component name, such as
+// LINK, with nodeRenderer. The node is the component call, whose literal
+// arguments ArgValue returns.
+func (r *Renderer) OverrideBuiltin(name string, nodeRenderer NodeRenderer) {
+	r.builtinOverrides[name] = nodeRenderer
+}
+
+// ArgValue returns the literal string, number or bool argument name of the
+// component call compCall, unescaped.
+func ArgValue(compCall ast.Node, name string) (string, bool) {
+	return argValue(compCall, name)
+}
+
+type override struct {
+	BaseRenderable
+	renderer     *Renderer
+	ruleName     string
+	nodeRenderer NodeRenderer
+}
+
+func newOverride(rend *Renderer, ruleName string, nodeRenderer NodeRenderer) RenderableNode {
+	return &override{
+		renderer:     rend,
+		ruleName:     ruleName,
+		nodeRenderer: nodeRenderer,
+	}
+}
+
+func (o *override) New() RenderableNode {
+	return newOverride(o.renderer, o.ruleName, o.nodeRenderer)
+}
+
+func (o *override) Condition(_ RenderableNode, node ast.Node) bool {
+	return ast.IsRuleName(node, o.ruleName)
+}
+
+func (o *override) Render() string {
+	return o.nodeRenderer.RenderNode(&Context{renderer: o.renderer, state: o.state, self: o}, o.Node())
+}
+
+type builtinOverride struct {
+	renderer     *Renderer
+	nodeRenderer NodeRenderer
+	builtin      builtinComponent
+}
+
+func (bo *builtinOverride) New() builtinComponent {
+	return &builtinOverride{
+		renderer:     bo.renderer,
+		nodeRenderer: bo.nodeRenderer,
+		builtin:      bo.builtin.New(),
+	}
+}
+
+func (bo *builtinOverride) Name() string {
+	return bo.builtin.Name()
+}
+
+func (bo *builtinOverride) Render(state *renderState, invoker RenderableNode, node ast.Node) string {
+	self := newOverride(bo.renderer, "", bo.nodeRenderer)
+	prepareNode(self, state, invoker, node)
+
+	ctx := &Context{
+		renderer: bo.renderer,
+		state:    state,
+		self:     self,
+		fallback: func() string {
+			return bo.builtin.Render(state, invoker, node)
+		},
+	}
+	return bo.nodeRenderer.RenderNode(ctx, node)
+}
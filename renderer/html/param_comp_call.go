@@ -7,8 +7,8 @@ import (
 )
 
 type paramCompCall struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
 type resolvedCompTarget struct {
@@ -16,17 +16,17 @@ type resolvedCompTarget struct {
 	scope ast.Node
 }
 
-func newParamCompCall(rend *renderer) renderableNode {
+func newParamCompCall(rend *Renderer) RenderableNode {
 	return &paramCompCall{
 		renderer: rend,
 	}
 }
 
-func (pcc *paramCompCall) New() renderableNode {
+func (pcc *paramCompCall) New() RenderableNode {
 	return newParamCompCall(pcc.renderer)
 }
 
-func (_ *paramCompCall) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *paramCompCall) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleNameOneOf(node, []string{"block-param-comp-call", "inline-param-comp-call"})
 }
 
@@ -41,7 +41,7 @@ func (pcc *paramCompCall) Render() string {
 
 	localCompDefSrc := target.scope
 	if localCompDefSrc == nil {
-		localCompDefSrc = localCompSourceFromNode(pcc.Node(), pcc.renderer.root)
+		localCompDefSrc = localCompSourceFromNode(pcc.Node(), pcc.state.root)
 	}
 
 	localCompDef := pcc.renderer.findLocalCompDef(localCompDefSrc, target.name)
@@ -61,10 +61,10 @@ func (pcc *paramCompCall) Render() string {
 		if inlineCall {
 			return pcc.renderInlineParamCompCall(localCompDefContent)
 		}
-		return pcc.renderer.renderChildren(pcc, localCompDefContent.Children())
+		return pcc.state.renderChildren(pcc, localCompDefContent.Children())
 	}
 
-	globalCompDef := pcc.renderer.findGlobalCompDef(target.name)
+	globalCompDef := pcc.state.findGlobalCompDef(localCompDefSrc, target.name)
 	if globalCompDef != nil {
 		globalCompDefContent := ast.FindNodeByRuleName(globalCompDef.Children(), "global-comp-def-content")
 		if globalCompDefContent == nil {
@@ -73,7 +73,7 @@ func (pcc *paramCompCall) Render() string {
 		if inlineCall {
 			return pcc.renderInlineParamCompCall(globalCompDefContent)
 		}
-		return pcc.renderer.renderChildren(pcc, globalCompDefContent.Children())
+		return pcc.state.renderChildren(pcc, globalCompDefContent.Children())
 	}
 
 	return ""
@@ -102,17 +102,17 @@ func (pcc *paramCompCall) resolveCompTarget(paramName string) resolvedCompTarget
 				return argName != nil && strings.TrimSpace(string(argName.Raw())) == paramName
 			})
 			if compCallArg != nil {
-				return resolveCompCallArgValueTarget(compCallArg, invokerAncestors, anc, pcc.renderer)
+				return resolveCompCallArgValueTarget(compCallArg, invokerAncestors, anc, pcc.state)
 			}
 		}
 
-		compDef := findCompDefFromCompCall(anc, pcc.renderer)
+		compDef := findCompDefFromCompCall(anc, pcc.state)
 		if compDef != nil {
 			val := getCompParamDefault(compDef, paramName)
 			if val != "" {
 				return resolvedCompTarget{
 					name:  val,
-					scope: localCompSourceFromNode(compDef, pcc.renderer.root),
+					scope: localCompSourceFromNode(compDef, pcc.state.root),
 				}
 			}
 		}
@@ -121,7 +121,7 @@ func (pcc *paramCompCall) resolveCompTarget(paramName string) resolvedCompTarget
 	return resolvedCompTarget{}
 }
 
-func resolveCompCallArgValueTarget(compCallArg ast.Node, invokerAncestors []ast.Node, currentCompCall ast.Node, r *renderer) resolvedCompTarget {
+func resolveCompCallArgValueTarget(compCallArg ast.Node, invokerAncestors []ast.Node, currentCompCall ast.Node, s *renderState) resolvedCompTarget {
 	compCallArgType := ast.FindNodeByRuleName(compCallArg.Children(), "comp-call-arg-type")
 	if compCallArgType == nil {
 		return resolvedCompTarget{}
@@ -147,16 +147,16 @@ func resolveCompCallArgValueTarget(compCallArg ast.Node, invokerAncestors []ast.
 				break
 			}
 		}
-		return resolveParamFromAncestorsTarget(referencedParamName, remainingAncestors, r)
+		return resolveParamFromAncestorsTarget(referencedParamName, remainingAncestors, s)
 	}
 
 	return resolvedCompTarget{
 		name:  strings.TrimSpace(string(argValue.Raw())),
-		scope: localCompSourceFromNode(currentCompCall, r.root),
+		scope: localCompSourceFromNode(currentCompCall, s.root),
 	}
 }
 
-func resolveParamFromAncestorsTarget(paramName string, invokerAncestors []ast.Node, r *renderer) resolvedCompTarget {
+func resolveParamFromAncestorsTarget(paramName string, invokerAncestors []ast.Node, s *renderState) resolvedCompTarget {
 	for _, anc := range invokerAncestors {
 		if !isCompCallLikeNode(anc) {
 			continue
@@ -170,18 +170,18 @@ func resolveParamFromAncestorsTarget(paramName string, invokerAncestors []ast.No
 			})
 
 			if compCallArg != nil {
-				return resolveCompCallArgValueTarget(compCallArg, invokerAncestors, anc, r)
+				return resolveCompCallArgValueTarget(compCallArg, invokerAncestors, anc, s)
 			}
 		}
 
-		if r != nil {
-			compDef := findCompDefFromCompCall(anc, r)
+		if s != nil {
+			compDef := findCompDefFromCompCall(anc, s)
 			if compDef != nil {
 				val := getCompParamDefault(compDef, paramName)
 				if val != "" {
 					return resolvedCompTarget{
 						name:  val,
-						scope: localCompSourceFromNode(compDef, r.root),
+						scope: localCompSourceFromNode(compDef, s.root),
 					}
 				}
 			}
@@ -243,8 +243,11 @@ func (pcc *paramCompCall) renderInlineParamCompCall(compDefContent ast.Node) str
 		return ""
 	}
 	p := ast.FindNodeByRuleName(compDefContent.Children(), "p")
+	if p == nil {
+		return ""
+	}
 	pContent := ast.FindNodeByRuleName(p.Children(), "p-content")
-	return pcc.renderer.renderChildren(pcc, pContent.Children())
+	return pcc.state.renderChildren(pcc, pContent.Children())
 }
 
 func localCompSourceFromNode(node ast.Node, root ast.Node) ast.Node {
@@ -1,15 +1,14 @@
 package html
 
 import (
-	"html"
 	"strings"
 
 	"github.com/umono-cms/compono/ast"
 )
 
 type baseParamRef struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
 func (bpr *baseParamRef) paramRefName() string {
@@ -17,12 +16,12 @@ func (bpr *baseParamRef) paramRefName() string {
 	return strings.TrimSpace(string(paramRefName.Raw()))
 }
 
-func renderCompParamCall(r *renderer, rn renderableNode, paramRefName string) string {
-	target := resolveParamFromAncestorsTarget(paramRefName, getAncestorsByInvoker(rn), r)
+func renderCompParamCall(s *renderState, rn RenderableNode, paramRefName string) string {
+	target := resolveParamFromAncestorsTarget(paramRefName, getAncestorsByInvoker(rn), s)
 	if target.name == "" {
 		return ""
 	}
-	if isCompTargetInInvokerChain(r, rn, target.name) {
+	if isCompTargetInInvokerChain(s, rn, target.name) {
 		return ""
 	}
 
@@ -30,16 +29,16 @@ func renderCompParamCall(r *renderer, rn renderableNode, paramRefName string) st
 
 	localCompDefSrc := target.scope
 	if localCompDefSrc == nil {
-		localCompDefSrc = localCompSourceFromNode(rn.Node(), r.root)
+		localCompDefSrc = localCompSourceFromNode(rn.Node(), s.root)
 	}
 
-	localCompDef := r.findLocalCompDef(localCompDefSrc, target.name)
+	localCompDef := s.renderer.findLocalCompDef(localCompDefSrc, target.name)
 	if localCompDef == nil {
 		currentGlobalCompDef := ast.FindNode(ast.GetAncestors(rn.Node()), func(anc ast.Node) bool {
 			return ast.IsRuleName(anc, "global-comp-def")
 		})
 		if currentGlobalCompDef != nil && currentGlobalCompDef != localCompDefSrc {
-			localCompDef = r.findLocalCompDef(currentGlobalCompDef, target.name)
+			localCompDef = s.renderer.findLocalCompDef(currentGlobalCompDef, target.name)
 		}
 	}
 	if localCompDef != nil {
@@ -48,34 +47,36 @@ func renderCompParamCall(r *renderer, rn renderableNode, paramRefName string) st
 			return ""
 		}
 		if inlineCall {
-			return renderInlineCompDefContent(r, rn, localCompDefContent)
+			return renderInlineCompDefContent(s, rn, localCompDefContent)
 		}
-		rendered := r.renderChildren(rn, localCompDefContent.Children())
-		if strings.Contains(rendered, "<compono-error-block>") {
+		blockErrCount := s.blockErrCount
+		rendered := s.renderChildren(rn, localCompDefContent.Children())
+		if s.blockErrCount > blockErrCount {
 			rendered = strings.ReplaceAll(rendered, "<br>", "</p><p>")
 		}
 		return rendered
 	}
 
-	globalCompDef := r.findGlobalCompDef(target.name)
+	globalCompDef := s.findGlobalCompDef(localCompDefSrc, target.name)
 	if globalCompDef != nil {
 		globalCompDefContent := ast.FindNodeByRuleName(globalCompDef.Children(), "global-comp-def-content")
 		if globalCompDefContent == nil {
 			return ""
 		}
 		if inlineCall {
-			return renderInlineCompDefContent(r, rn, globalCompDefContent)
+			return renderInlineCompDefContent(s, rn, globalCompDefContent)
 		}
-		rendered := r.renderChildren(rn, globalCompDefContent.Children())
-		if strings.Contains(rendered, "<compono-error-block>") {
+		blockErrCount := s.blockErrCount
+		rendered := s.renderChildren(rn, globalCompDefContent.Children())
+		if s.blockErrCount > blockErrCount {
 			rendered = strings.ReplaceAll(rendered, "<br>", "</p><p>")
 		}
 		return rendered
 	}
 
-	builtinComp := r.findBuiltinComp(target.name)
+	builtinComp := s.findBuiltinComp(target.name)
 	if builtinComp != nil {
-		return builtinComp.Render(rn.Invoker(), rn.Node())
+		return builtinComp.Render(s, rn.Invoker(), rn.Node())
 	}
 
 	return ""
@@ -85,7 +86,7 @@ type paramRefInLocalCompDef struct {
 	baseParamRef
 }
 
-func newParamRefInLocalCompDef(rend *renderer) renderableNode {
+func newParamRefInLocalCompDef(rend *Renderer) RenderableNode {
 	return &paramRefInLocalCompDef{
 		baseParamRef: baseParamRef{
 			renderer: rend,
@@ -93,11 +94,11 @@ func newParamRefInLocalCompDef(rend *renderer) renderableNode {
 	}
 }
 
-func (p *paramRefInLocalCompDef) New() renderableNode {
+func (p *paramRefInLocalCompDef) New() RenderableNode {
 	return newParamRefInLocalCompDef(p.renderer)
 }
 
-func (_ *paramRefInLocalCompDef) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *paramRefInLocalCompDef) Condition(invoker RenderableNode, node ast.Node) bool {
 	if !ast.IsRuleName(node, "param-ref") {
 		return false
 	}
@@ -121,8 +122,8 @@ func (p *paramRefInLocalCompDef) Render() string {
 	}
 
 	if compParam != nil {
-		if shouldTreatParamRefAsCompCall(compParam, p, p.renderer, paramRefName) {
-			return renderCompParamCall(p.renderer, p, paramRefName)
+		if shouldTreatParamRefAsCompCall(compParam, p, p.state, paramRefName) {
+			return renderCompParamCall(p.state, p, paramRefName)
 		}
 
 		compCall := ast.FindNode(getAncestorsByInvoker(p), func(node ast.Node) bool {
@@ -137,7 +138,7 @@ func (p *paramRefInLocalCompDef) Render() string {
 					return strings.TrimSpace(string(argName.Raw())) == paramRefName
 				})
 				if compCallArg != nil {
-					return resolveCompCallArgValue(compCallArg, getAncestorsByInvoker(p), compCall, p.renderer)
+					return resolveCompCallArgValue(compCallArg, getAncestorsByInvoker(p), compCall, p.state)
 				}
 			}
 		}
@@ -154,7 +155,7 @@ func (p *paramRefInLocalCompDef) Render() string {
 			return ""
 		}
 
-		return html.EscapeString(strings.TrimSpace(string(compParamDefaValue.Raw())))
+		return p.renderer.escapeText(strings.TrimSpace(string(compParamDefaValue.Raw())))
 	}
 
 	globalCompDef := ast.FindNodeByRuleName(ast.GetAncestors(p.Node()), "global-comp-def")
@@ -194,7 +195,7 @@ func (p *paramRefInLocalCompDef) Render() string {
 			return strings.TrimSpace(string(argName.Raw())) == paramRefName
 		})
 		if compCallArg != nil {
-			return resolveCompCallArgValue(compCallArg, getAncestorsByInvoker(p), anc, p.renderer)
+			return resolveCompCallArgValue(compCallArg, getAncestorsByInvoker(p), anc, p.state)
 		}
 	}
 
@@ -206,14 +207,14 @@ func (p *paramRefInLocalCompDef) Render() string {
 		return ""
 	}
 
-	return html.EscapeString(strings.TrimSpace(string(compParamDefaValue.Raw())))
+	return p.renderer.escapeText(strings.TrimSpace(string(compParamDefaValue.Raw())))
 }
 
 type paramRefInGlobalCompDef struct {
 	baseParamRef
 }
 
-func newParamRefInGlobalCompDef(rend *renderer) renderableNode {
+func newParamRefInGlobalCompDef(rend *Renderer) RenderableNode {
 	return &paramRefInGlobalCompDef{
 		baseParamRef: baseParamRef{
 			renderer: rend,
@@ -221,11 +222,11 @@ func newParamRefInGlobalCompDef(rend *renderer) renderableNode {
 	}
 }
 
-func (p *paramRefInGlobalCompDef) New() renderableNode {
+func (p *paramRefInGlobalCompDef) New() RenderableNode {
 	return newParamRefInGlobalCompDef(p.renderer)
 }
 
-func (_ *paramRefInGlobalCompDef) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *paramRefInGlobalCompDef) Condition(invoker RenderableNode, node ast.Node) bool {
 	if !ast.IsRuleName(node, "param-ref") {
 		return false
 	}
@@ -252,8 +253,8 @@ func (p *paramRefInGlobalCompDef) Render() string {
 	if compParam == nil {
 		return ""
 	}
-	if shouldTreatParamRefAsCompCall(compParam, p, p.renderer, paramRefName) {
-		return renderCompParamCall(p.renderer, p, paramRefName)
+	if shouldTreatParamRefAsCompCall(compParam, p, p.state, paramRefName) {
+		return renderCompParamCall(p.state, p, paramRefName)
 	}
 
 	compCall := ast.FindNode(getAncestorsByInvoker(p), func(node ast.Node) bool {
@@ -268,7 +269,7 @@ func (p *paramRefInGlobalCompDef) Render() string {
 				return strings.TrimSpace(string(argName.Raw())) == paramRefName
 			})
 			if compCallArg != nil {
-				return resolveCompCallArgValue(compCallArg, getAncestorsByInvoker(p), compCall, p.renderer)
+				return resolveCompCallArgValue(compCallArg, getAncestorsByInvoker(p), compCall, p.state)
 			}
 		}
 	}
@@ -281,10 +282,10 @@ func (p *paramRefInGlobalCompDef) Render() string {
 		return ""
 	}
 
-	return html.EscapeString(strings.TrimSpace(string(compParamDefaValue.Raw())))
+	return p.renderer.escapeText(strings.TrimSpace(string(compParamDefaValue.Raw())))
 }
 
-func resolveCompCallArgValue(compCallArg ast.Node, invokerAncestors []ast.Node, currentCompCall ast.Node, r ...*renderer) string {
+func resolveCompCallArgValue(compCallArg ast.Node, invokerAncestors []ast.Node, currentCompCall ast.Node, s ...*renderState) string {
 	compCallArgType := ast.FindNodeByRuleName(compCallArg.Children(), "comp-call-arg-type")
 	if compCallArgType == nil {
 		return ""
@@ -310,17 +311,21 @@ func resolveCompCallArgValue(compCallArg ast.Node, invokerAncestors []ast.Node,
 				break
 			}
 		}
-		var rend *renderer
-		if len(r) > 0 {
-			rend = r[0]
+		var state *renderState
+		if len(s) > 0 {
+			state = s[0]
 		}
-		return resolveParamFromAncestors(referencedParamName, remainingAncestors, rend)
+		return resolveParamFromAncestors(referencedParamName, remainingAncestors, state)
 	}
 
-	return html.EscapeString(strings.TrimSpace(string(argValue.Raw())))
+	var state *renderState
+	if len(s) > 0 {
+		state = s[0]
+	}
+	return state.escapeText(strings.TrimSpace(string(argValue.Raw())))
 }
 
-func resolveParamFromAncestors(paramName string, invokerAncestors []ast.Node, r *renderer) string {
+func resolveParamFromAncestors(paramName string, invokerAncestors []ast.Node, s *renderState) string {
 	for i, anc := range invokerAncestors {
 		if !isCompCallLikeNode(anc) {
 			continue
@@ -334,23 +339,23 @@ func resolveParamFromAncestors(paramName string, invokerAncestors []ast.Node, r
 			})
 
 			if compCallArg != nil {
-				return resolveCompCallArgValue(compCallArg, invokerAncestors, anc, r)
+				return resolveCompCallArgValue(compCallArg, invokerAncestors, anc, s)
 			}
 		}
 
-		if r != nil {
+		if s != nil {
 			if ast.IsRuleName(anc, "param-ref") {
 				paramRefName := getParamRefNameStr(anc)
 				if paramRefName != "" {
-					target := resolveParamFromAncestorsTarget(paramRefName, invokerAncestors[i+1:], r)
+					target := resolveParamFromAncestorsTarget(paramRefName, invokerAncestors[i+1:], s)
 					if target.name != "" {
-						compDef := r.findLocalCompDef(target.scope, target.name)
+						compDef := s.renderer.findLocalCompDef(target.scope, target.name)
 						if compDef == nil {
-							compDef = r.findGlobalCompDef(target.name)
+							compDef = s.findGlobalCompDef(target.scope, target.name)
 						}
 						if compDef != nil {
 							if defaValue := getCompParamDefault(compDef, paramName); defaValue != "" {
-								return html.EscapeString(defaValue)
+								return s.escapeText(defaValue)
 							}
 						}
 					}
@@ -358,7 +363,7 @@ func resolveParamFromAncestors(paramName string, invokerAncestors []ast.Node, r
 				continue
 			}
 
-			val := resolveParamDefaultFromCompCall(anc, paramName, r)
+			val := resolveParamDefaultFromCompCall(anc, paramName, s)
 			if val != "" {
 				return val
 			}
@@ -367,8 +372,8 @@ func resolveParamFromAncestors(paramName string, invokerAncestors []ast.Node, r
 	return ""
 }
 
-func resolveParamDefaultFromCompCall(compCallNode ast.Node, paramName string, r *renderer) string {
-	compDef := findCompDefFromCompCall(compCallNode, r)
+func resolveParamDefaultFromCompCall(compCallNode ast.Node, paramName string, s *renderState) string {
+	compDef := findCompDefFromCompCall(compCallNode, s)
 	if compDef == nil {
 		return ""
 	}
@@ -412,10 +417,10 @@ func resolveParamDefaultFromCompCall(compCallNode ast.Node, paramName string, r
 		return ""
 	}
 
-	return html.EscapeString(strings.TrimSpace(string(defaValue.Raw())))
+	return s.escapeText(strings.TrimSpace(string(defaValue.Raw())))
 }
 
-func findCompDefFromCompCall(compCallNode ast.Node, r *renderer) ast.Node {
+func findCompDefFromCompCall(compCallNode ast.Node, s *renderState) ast.Node {
 	compCallNameNode := ast.FindNodeByRuleName(compCallNode.Children(), "comp-call-name")
 	if compCallNameNode == nil {
 		return nil
@@ -426,17 +431,17 @@ func findCompDefFromCompCall(compCallNode ast.Node, r *renderer) ast.Node {
 		return ast.IsRuleName(anc, "global-comp-def")
 	})
 
-	localCompDefSrc := r.root
+	localCompDefSrc := s.root
 	if globalCompDefAnc != nil {
 		localCompDefSrc = globalCompDefAnc
 	}
 
-	localCompDef := r.findLocalCompDef(localCompDefSrc, compName)
+	localCompDef := s.renderer.findLocalCompDef(localCompDefSrc, compName)
 	if localCompDef != nil {
 		return localCompDef
 	}
 
-	return r.findGlobalCompDef(compName)
+	return s.findGlobalCompDef(compCallNode, compName)
 }
 
 func getParamRefNameStr(node ast.Node) string {
@@ -447,7 +452,7 @@ func getParamRefNameStr(node ast.Node) string {
 	return ""
 }
 
-func renderInlineCompDefContent(r *renderer, invoker renderableNode, compDefContent ast.Node) string {
+func renderInlineCompDefContent(s *renderState, invoker RenderableNode, compDefContent ast.Node) string {
 	childCount := len(compDefContent.Children())
 	if childCount == 0 {
 		return ""
@@ -461,7 +466,7 @@ func renderInlineCompDefContent(r *renderer, invoker renderableNode, compDefCont
 		return ""
 	}
 
-	return r.renderChildren(invoker, pContent.Children())
+	return s.renderChildren(invoker, pContent.Children())
 }
 
 func isInlineCompParamRef(node ast.Node) bool {
@@ -562,7 +567,7 @@ func findCompParamByName(compDef ast.Node, name string) ast.Node {
 	})
 }
 
-func isCompTargetInInvokerChain(r *renderer, rn renderableNode, targetName string) bool {
+func isCompTargetInInvokerChain(s *renderState, rn RenderableNode, targetName string) bool {
 	ancestors := getAncestorsByInvoker(rn)
 	for i, anc := range ancestors {
 		if ast.IsRuleNameOneOf(anc, []string{"block-comp-call", "inline-comp-call"}) {
@@ -581,7 +586,7 @@ func isCompTargetInInvokerChain(r *renderer, rn renderableNode, targetName strin
 		if paramRefName == "" {
 			continue
 		}
-		resolved := resolveParamFromAncestorsTarget(paramRefName, ancestors[i+1:], r)
+		resolved := resolveParamFromAncestorsTarget(paramRefName, ancestors[i+1:], s)
 		if resolved.name == targetName {
 			return true
 		}
@@ -590,7 +595,7 @@ func isCompTargetInInvokerChain(r *renderer, rn renderableNode, targetName strin
 	return false
 }
 
-func shouldTreatParamRefAsCompCall(compParam ast.Node, rn renderableNode, r *renderer, paramRefName string) bool {
+func shouldTreatParamRefAsCompCall(compParam ast.Node, rn RenderableNode, s *renderState, paramRefName string) bool {
 	paramType := ast.GetTypeFromCompParam(compParam)
 	if paramType == "comp" {
 		return true
@@ -605,11 +610,11 @@ func shouldTreatParamRefAsCompCall(compParam ast.Node, rn renderableNode, r *ren
 		}
 	}
 
-	if rn == nil || r == nil {
+	if rn == nil || s == nil {
 		return false
 	}
 
-	target := resolveParamFromAncestorsTarget(paramRefName, getAncestorsByInvoker(rn), r)
+	target := resolveParamFromAncestorsTarget(paramRefName, getAncestorsByInvoker(rn), s)
 	if target.name == "" {
 		return false
 	}
@@ -617,11 +622,11 @@ func shouldTreatParamRefAsCompCall(compParam ast.Node, rn renderableNode, r *ren
 		return true
 	}
 
-	if r.findBuiltinCompDef(target.name) != nil {
+	if s.findBuiltinCompDef(target.name) != nil {
 		return true
 	}
-	if r.findLocalCompDef(target.scope, target.name) != nil {
+	if s.renderer.findLocalCompDef(target.scope, target.name) != nil {
 		return true
 	}
-	return r.findGlobalCompDef(target.name) != nil
+	return s.findGlobalCompDef(target.scope, target.name) != nil
 }
@@ -1,39 +1,40 @@
 package html
 
 import (
-	"html"
 	"regexp"
 
 	"github.com/umono-cms/compono/ast"
 )
 
+var (
+	plainJunkRe  = regexp.MustCompile(`[\t\n\r\f\v]+`)
+	plainEdgesRe = regexp.MustCompile(`^\s{2,}|\s{2,}$`)
+)
+
 type plain struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newPlain(rend *renderer) renderableNode {
+func newPlain(rend *Renderer) RenderableNode {
 	return &plain{
 		renderer: rend,
 	}
 }
 
-func (p *plain) New() renderableNode {
+func (p *plain) New() RenderableNode {
 	return newPlain(p.renderer)
 }
 
-func (_ *plain) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *plain) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "plain")
 }
 
 func (p *plain) Render() string {
-	return html.EscapeString(p.normalizeEdges(string(p.Node().Raw())))
+	return p.renderer.escapeText(p.normalizeEdges(string(p.Node().Raw())))
 }
 
 func (_ *plain) normalizeEdges(s string) string {
-	reJunk := regexp.MustCompile(`[\t\n\r\f\v]+`)
-	s = reJunk.ReplaceAllString(s, "")
-
-	re := regexp.MustCompile(`^\s{2,}|\s{2,}$`)
-	return re.ReplaceAllString(s, " ")
+	s = plainJunkRe.ReplaceAllString(s, "")
+	return plainEdgesRe.ReplaceAllString(s, " ")
 }
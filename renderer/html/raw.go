@@ -7,21 +7,21 @@ import (
 )
 
 type raw struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newRaw(rend *renderer) renderableNode {
+func newRaw(rend *Renderer) RenderableNode {
 	return &raw{
 		renderer: rend,
 	}
 }
 
-func (r *raw) New() renderableNode {
+func (r *raw) New() RenderableNode {
 	return newRaw(r.renderer)
 }
 
-func (_ *raw) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *raw) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "raw")
 }
 
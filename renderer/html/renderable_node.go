@@ -4,41 +4,65 @@ import (
 	"github.com/umono-cms/compono/ast"
 )
 
-type renderableNode interface {
-	New() renderableNode
-	Condition(invoker renderableNode, node ast.Node) bool
+// RenderableNode renders the nodes it accepts in Condition. New returns a
+// fresh instance for each node, which is rendered with Node and Invoker set.
+type RenderableNode interface {
+	New() RenderableNode
+	Condition(invoker RenderableNode, node ast.Node) bool
 	Render() string
 
-	Invoker() renderableNode
-	SetInvoker(renderableNode)
+	Invoker() RenderableNode
+	SetInvoker(RenderableNode)
 
 	Node() ast.Node
 	SetNode(ast.Node)
 }
 
-type baseRenderable struct {
-	invoker renderableNode
+// BaseRenderable implements the Invoker and Node accessors of a
+// RenderableNode, and holds the state of the Render call the node is
+// rendered in.
+type BaseRenderable struct {
+	invoker RenderableNode
 	node    ast.Node
+	state   *renderState
 }
 
-func (br *baseRenderable) Invoker() renderableNode {
+func (br *BaseRenderable) setState(state *renderState) {
+	br.state = state
+}
+
+func (br *BaseRenderable) Invoker() RenderableNode {
 	return br.invoker
 }
 
-func (br *baseRenderable) SetInvoker(invoker renderableNode) {
+func (br *BaseRenderable) SetInvoker(invoker RenderableNode) {
 	br.invoker = invoker
 }
 
-func (br *baseRenderable) Node() ast.Node {
+func (br *BaseRenderable) Node() ast.Node {
 	return br.node
 }
 
-func (br *baseRenderable) SetNode(node ast.Node) {
+func (br *BaseRenderable) SetNode(node ast.Node) {
 	br.node = node
 }
 
-func renderNode(rn renderableNode, invoker renderableNode, node ast.Node) string {
+// stateHolder is implemented by the renderables that embed BaseRenderable.
+type stateHolder interface {
+	setState(state *renderState)
+}
+
+func renderNode(rn RenderableNode, state *renderState, invoker RenderableNode, node ast.Node) string {
+	prepareNode(rn, state, invoker, node)
+	return rn.Render()
+}
+
+// prepareNode sets the node rn renders, the renderable that invoked it and
+// the state of the Render call.
+func prepareNode(rn RenderableNode, state *renderState, invoker RenderableNode, node ast.Node) {
+	if sh, ok := rn.(stateHolder); ok {
+		sh.setState(state)
+	}
 	rn.SetInvoker(invoker)
 	rn.SetNode(node)
-	return rn.Render()
 }
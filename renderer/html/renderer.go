@@ -1,6 +1,8 @@
 package html
 
 import (
+	"bufio"
+	"html"
 	"io"
 	"strings"
 
@@ -8,19 +10,51 @@ import (
 	"github.com/umono-cms/compono/logger"
 )
 
-type renderer struct {
-	logger          logger.Logger
-	renderableNodes []renderableNode
+// Renderer holds the configuration of the HTML rendering. The state of a
+// Render call is passed down to the renderables it renders, so a Renderer
+// can render several roots at once.
+type Renderer struct {
+	logger           logger.Logger
+	renderableNodes  []RenderableNode
+	overrides        map[string]NodeRenderer
+	builtinOverrides map[string]NodeRenderer
+	textEscaper      func(s string) string
+	builtinCompMap   map[string]builtinComponent
+	errorRenderer    ErrorRenderer
+}
+
+// renderState is the state of a Render call. Renderables reach it through
+// their BaseRenderable and render their children through it.
+type renderState struct {
+	renderer        *Renderer
 	root            ast.Node
-	builtinCompMap  map[string]builtinComponent
+	blockErrCount   int
+	blockErrOutputs map[string]struct{}
+	// contents holds the page and layout contents waiting for their
+	// layout's CONTENT call, innermost last.
+	contents []ast.Node
+	// out and writeErr are the output of the call and its first write
+	// error.
+	out      *bufio.Writer
+	writeErr error
 }
 
-func NewRenderer(log logger.Logger) *renderer {
-	r := &renderer{
-		logger: log,
+func NewRenderer(log logger.Logger) *Renderer {
+	r := &Renderer{
+		logger:           log,
+		errorRenderer:    NewErrorRenderer(ErrorModeWebComponent),
+		overrides:        map[string]NodeRenderer{},
+		builtinOverrides: map[string]NodeRenderer{},
 	}
+	r.registerRenderables()
+
+	return r
+}
 
-	r.renderableNodes = []renderableNode{
+// registerRenderables creates the renderables and builtin components of r,
+// which are configured by r.
+func (r *Renderer) registerRenderables() {
+	r.renderableNodes = []RenderableNode{
 		newErr(r),
 		newRoot(r),
 		newRootContent(r),
@@ -45,46 +79,116 @@ func NewRenderer(log logger.Logger) *renderer {
 
 	builtinComps := []builtinComponent{
 		newLink(r),
+		newContent(r),
 	}
 
 	for _, bc := range builtinComps {
 		r.builtinCompMap[bc.Name()] = bc
 	}
+}
 
-	return r
+// SetTextEscaper sets the function that escapes plain text and parameter
+// values, html.EscapeString by default. Renderers built on this one use it
+// to escape for their own output format.
+func (r *Renderer) SetTextEscaper(escape func(s string) string) {
+	r.textEscaper = escape
 }
 
-func (r *renderer) Render(writer io.Writer, root ast.Node) error {
-	r.root = root
+// escapeText escapes s with the text escaper of r, which may be nil.
+func (r *Renderer) escapeText(s string) string {
+	if r == nil || r.textEscaper == nil {
+		return html.EscapeString(s)
+	}
+	return r.textEscaper(s)
+}
 
-	_, err := writer.Write([]byte(r.render(root)))
-	if err != nil {
-		return err
+func (r *Renderer) SetErrorRenderer(errorRenderer ErrorRenderer) {
+	r.errorRenderer = errorRenderer
+}
+
+// Render writes root to writer as its top-level blocks are rendered, so
+// that the start of a page reaches writer before its end is rendered. The
+// first write error stops rendering and is returned.
+func (r *Renderer) Render(writer io.Writer, root ast.Node) error {
+	out, ok := writer.(*bufio.Writer)
+	if !ok {
+		out = bufio.NewWriter(writer)
 	}
 
-	return nil
+	state := &renderState{
+		renderer:        r,
+		root:            root,
+		blockErrOutputs: map[string]struct{}{},
+		out:             out,
+	}
+	state.stream(nil, root)
+	if state.writeErr != nil {
+		return state.writeErr
+	}
+
+	return out.Flush()
+}
+
+// streamer is implemented by the renderables whose children are written to
+// the output one by one instead of being rendered to a string first.
+type streamer interface {
+	stream()
 }
 
-func (r *renderer) render(node ast.Node) string {
-	rn := r.findRenderable(nil, node)
-	if rn != nil {
-		return renderNode(rn, nil, node)
+func (s *renderState) stream(invoker RenderableNode, node ast.Node) {
+	if s.writeErr != nil {
+		return
+	}
+
+	rn := s.renderer.findRenderable(invoker, node)
+	if rn == nil {
+		return
+	}
+
+	st, ok := rn.(streamer)
+	if !ok {
+		s.write(renderNode(rn, s, invoker, node))
+		return
+	}
+
+	prepareNode(rn, s, invoker, node)
+	st.stream()
+}
+
+func (s *renderState) streamChildren(invoker RenderableNode, children []ast.Node) {
+	for _, child := range children {
+		s.stream(invoker, child)
 	}
-	return ""
 }
 
-func (r *renderer) renderChildren(invoker renderableNode, children []ast.Node) string {
-	result := ""
+func (s *renderState) write(rendered string) {
+	if s.writeErr != nil || rendered == "" {
+		return
+	}
+	_, s.writeErr = s.out.WriteString(rendered)
+}
+
+func (s *renderState) renderChildren(invoker RenderableNode, children []ast.Node) string {
+	var sb strings.Builder
 	for _, child := range children {
-		re := r.findRenderable(invoker, child)
+		re := s.renderer.findRenderable(invoker, child)
 		if re != nil {
-			result += renderNode(re, invoker, child)
+			sb.WriteString(renderNode(re, s, invoker, child))
 		}
 	}
-	return result
+	return sb.String()
 }
 
-func (r *renderer) findRenderable(invoker renderableNode, node ast.Node) renderableNode {
+func (r *Renderer) findRenderable(invoker RenderableNode, node ast.Node) RenderableNode {
+	if node.Rule() != nil {
+		if nodeRenderer, ok := r.overrides[node.Rule().Name()]; ok {
+			return newOverride(r, node.Rule().Name(), nodeRenderer)
+		}
+	}
+	return r.findDefaultRenderable(invoker, node)
+}
+
+func (r *Renderer) findDefaultRenderable(invoker RenderableNode, node ast.Node) RenderableNode {
 	for _, rn := range r.renderableNodes {
 		if cond := rn.Condition(invoker, node); cond {
 			return rn.New()
@@ -93,7 +197,50 @@ func (r *renderer) findRenderable(invoker renderableNode, node ast.Node) rendera
 	return nil
 }
 
-func (r *renderer) findLocalCompDef(srcNode ast.Node, name string) ast.Node {
+// renderContent renders the children of a root or global component
+// content. When the content declares a layout, the layout is rendered
+// instead, with the content at its CONTENT call.
+func (s *renderState) renderContent(invoker RenderableNode, content ast.Node) string {
+	var sb strings.Builder
+	s.visitContent(content, func(children []ast.Node) {
+		sb.WriteString(s.renderChildren(invoker, children))
+	})
+	return sb.String()
+}
+
+// streamContent is renderContent writing the children to the output.
+func (s *renderState) streamContent(invoker RenderableNode, content ast.Node) {
+	s.visitContent(content, func(children []ast.Node) {
+		s.streamChildren(invoker, children)
+	})
+}
+
+// visitContent calls visit with the children to render for content, those
+// of its outermost layout while the inner contents wait on the contents
+// stack.
+func (s *renderState) visitContent(content ast.Node, visit func(children []ast.Node)) {
+	layoutDecl := ast.GetLayoutDecl(content)
+	if layoutDecl == nil {
+		visit(content.Children())
+		return
+	}
+
+	layoutDef := s.findGlobalCompDef(layoutDecl, ast.GetLayoutNameFromLayoutDecl(layoutDecl))
+	if layoutDef == nil {
+		visit(content.Children())
+		return
+	}
+	layoutContent := ast.FindNodeByRuleName(layoutDef.Children(), "global-comp-def-content")
+	if layoutContent == nil {
+		return
+	}
+
+	s.contents = append(s.contents, content)
+	s.visitContent(layoutContent, visit)
+	s.contents = s.contents[:len(s.contents)-1]
+}
+
+func (r *Renderer) findLocalCompDef(srcNode ast.Node, name string) ast.Node {
 	localCompDefWrapper := ast.FindNodeByRuleName(srcNode.Children(), "local-comp-def-wrapper")
 	if localCompDefWrapper == nil {
 		return nil
@@ -122,42 +269,58 @@ func (r *renderer) findLocalCompDef(srcNode ast.Node, name string) ast.Node {
 	})
 }
 
-func (r *renderer) findGlobalCompDef(name string) ast.Node {
-	globalCompDefWrapper := ast.FindNodeByRuleName(r.root.Children(), "global-comp-def-wrapper")
-	if globalCompDefWrapper == nil {
-		return nil
-	}
-
-	return ast.FindNode(globalCompDefWrapper.Children(), func(child ast.Node) bool {
-		if !ast.IsRuleName(child, "global-comp-def") {
-			return false
-		}
-
-		globalCompName := ast.FindNodeByRuleName(child.Children(), "global-comp-name")
-		if globalCompName == nil {
-			return false
-		}
-
-		if strings.TrimSpace(string(globalCompName.Raw())) != strings.TrimSpace(name) {
-			return false
-		}
-
-		return true
-	})
+// findGlobalCompDef returns the global component name refers to from
+// scope, looking in the namespace of the global component scope is in
+// first.
+func (s *renderState) findGlobalCompDef(scope ast.Node, name string) ast.Node {
+	return ast.ResolveGlobalCompDef(s.root, scope, name)
 }
 
-func (r *renderer) findBuiltinComp(name string) builtinComponent {
-	if r.findBuiltinCompDef(name) == nil {
+func (s *renderState) findBuiltinComp(name string) builtinComponent {
+	if s.findBuiltinCompDef(name) == nil {
 		return nil
 	}
 
+	r := s.renderer
 	bc, ok := r.builtinCompMap[strings.TrimSpace(name)]
 	if !ok {
 		return nil
 	}
+	if nodeRenderer, ok := r.builtinOverrides[bc.Name()]; ok {
+		return &builtinOverride{
+			renderer:     r,
+			nodeRenderer: nodeRenderer,
+			builtin:      bc.New(),
+		}
+	}
 	return bc.New()
 }
 
-func (r *renderer) findBuiltinCompDef(name string) ast.Node {
-	return ast.FindBuiltinCompDef(r.root, name)
+func (s *renderState) findBuiltinCompDef(name string) ast.Node {
+	return ast.FindBuiltinCompDef(s.root, name)
+}
+
+// escapeText escapes s with the text escaper of the renderer of the call,
+// html.EscapeString when there is none.
+func (s *renderState) escapeText(text string) string {
+	if s == nil {
+		return html.EscapeString(text)
+	}
+	return s.renderer.escapeText(text)
+}
+
+func (s *renderState) markBlockErr(rendered string) {
+	s.blockErrCount++
+	if rendered != "" {
+		s.blockErrOutputs[rendered] = struct{}{}
+	}
+}
+
+func (s *renderState) isBlockErr(rendered string) bool {
+	for output := range s.blockErrOutputs {
+		if strings.HasPrefix(rendered, output) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,121 @@
+package html_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/logger"
+	"github.com/umono-cms/compono/parser"
+	"github.com/umono-cms/compono/renderer/html"
+)
+
+func largePage(blocks int) []byte {
+	var sb strings.Builder
+	for i := 0; i < blocks; i++ {
+		sb.WriteString("## Section\n\nSome **bold** and *italic* text with `code`.\n\n{{ CARD title=\"Card\" }}\n\n")
+	}
+	sb.WriteString("~ CARD title=\"\"\n### {{ title }}\n\nA card with a {{ BADGE }}.\n\n~ BADGE\n*badge*")
+	return []byte(sb.String())
+}
+
+func parseAndWrap(source []byte) ast.Node {
+	root := parser.DefaultParser(logger.NewLogger()).Parse(source, ast.DefaultRootNode())
+	errwrap.DefaultErrorWrapper().Wrap(root)
+	return root
+}
+
+type recordingWriter struct {
+	writes int
+	sb     strings.Builder
+	err    error
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.writes++
+	return w.sb.Write(p)
+}
+
+func TestRenderStreams(t *testing.T) {
+	root := parseAndWrap(largePage(20))
+	r := html.NewRenderer(logger.NewLogger())
+
+	var whole strings.Builder
+	require.Nil(t, r.Render(&whole, root))
+	assert.Contains(t, whole.String(), "<h3>Card</h3><p>A card with a <em>badge</em>.</p>")
+
+	w := &recordingWriter{}
+	require.Nil(t, r.Render(bufio.NewWriterSize(w, 64), root))
+	assert.Equal(t, whole.String(), w.sb.String())
+	assert.Greater(t, w.writes, 20)
+}
+
+func TestRenderWriteError(t *testing.T) {
+	root := parseAndWrap(largePage(20))
+	r := html.NewRenderer(logger.NewLogger())
+
+	errWrite := errors.New("connection closed")
+	err := r.Render(bufio.NewWriterSize(&recordingWriter{err: errWrite}, 64), root)
+	assert.ErrorIs(t, err, errWrite)
+
+	err = r.Render(&recordingWriter{err: errWrite}, root)
+	assert.ErrorIs(t, err, errWrite)
+}
+
+func TestRenderConcurrently(t *testing.T) {
+	r := html.NewRenderer(logger.NewLogger())
+	roots := []ast.Node{
+		parseAndWrap(largePage(5)),
+		parseAndWrap([]byte("{{ PAGE }}\n\n~ PAGE\n{{ UNKNOWN }}\n\nText")),
+	}
+
+	want := make([]string, len(roots))
+	for i, root := range roots {
+		var sb strings.Builder
+		require.Nil(t, r.Render(&sb, root))
+		want[i] = sb.String()
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				i := j % len(roots)
+				var sb strings.Builder
+				assert.Nil(t, r.Render(&sb, roots[i]))
+				assert.Equal(t, want[i], sb.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func benchmarkRender(b *testing.B, blocks int) {
+	root := parseAndWrap(largePage(blocks))
+	r := html.NewRenderer(logger.NewLogger())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Render(io.Discard, root); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderSmall(b *testing.B) { benchmarkRender(b, 10) }
+
+func BenchmarkRenderLarge(b *testing.B) { benchmarkRender(b, 500) }
@@ -5,47 +5,55 @@ import (
 )
 
 type root struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newRoot(rend *renderer) renderableNode {
+func newRoot(rend *Renderer) RenderableNode {
 	return &root{
 		renderer: rend,
 	}
 }
 
-func (r *root) New() renderableNode {
+func (r *root) New() RenderableNode {
 	return newRoot(r.renderer)
 }
 
-func (_ *root) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *root) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "root")
 }
 
 func (r *root) Render() string {
-	return r.renderer.renderChildren(r, r.Node().Children())
+	return r.state.renderChildren(r, r.Node().Children())
+}
+
+func (r *root) stream() {
+	r.state.streamChildren(r, r.Node().Children())
 }
 
 type rootContent struct {
-	baseRenderable
-	renderer *renderer
+	BaseRenderable
+	renderer *Renderer
 }
 
-func newRootContent(rend *renderer) renderableNode {
+func newRootContent(rend *Renderer) RenderableNode {
 	return &rootContent{
 		renderer: rend,
 	}
 }
 
-func (rc *rootContent) New() renderableNode {
+func (rc *rootContent) New() RenderableNode {
 	return newRootContent(rc.renderer)
 }
 
-func (_ *rootContent) Condition(invoker renderableNode, node ast.Node) bool {
+func (_ *rootContent) Condition(invoker RenderableNode, node ast.Node) bool {
 	return ast.IsRuleName(node, "root-content")
 }
 
 func (rc *rootContent) Render() string {
-	return rc.renderer.renderChildren(rc, rc.Node().Children())
+	return rc.state.renderContent(rc, rc.Node())
+}
+
+func (rc *rootContent) stream() {
+	rc.state.streamContent(rc, rc.Node())
 }
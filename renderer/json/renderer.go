@@ -0,0 +1,255 @@
+// Package json renders compono content as a JSON document tree, for
+// frontends that render structured content themselves.
+//
+// The document has the schema version, the content nodes and the
+// diagnostics of the content:
+//
+//	{"version": 1, "children": [...], "diagnostics": [...]}
+//
+// Every node has a type:
+//
+//	{"type": "heading", "level": 1, "children": [...]}
+//	{"type": "paragraph", "children": [...]}
+//	{"type": "text", "value": "..."}
+//	{"type": "emphasis", "children": [...]}
+//	{"type": "strong", "children": [...]}
+//	{"type": "code", "value": "..."}
+//	{"type": "codeBlock", "lang": "go", "value": "..."}
+//	{"type": "link", "href": "...", "newTab": false, "rel": "...", "target": "...", "children": [...]}
+//	{"type": "break"}
+//	{"type": "error", "diagnostic": {...}}
+//	{"type": "warning", "diagnostic": {...}}
+//	{"type": "component", "name": "CARD", "args": {...}, "children": [...]}
+//
+// Component calls are expanded into the nodes of their content. With
+// SetPreserveComponents, each call is kept as a component node instead,
+// whose children are the expansion and whose args map argument names to
+// strings, numbers and bools, and component and parameter arguments to the
+// name they refer to. Calls of the LINK builtin become link nodes. A link
+// has rel and target only when the link resolver set them; newTab is true
+// for a LINK that opens in a new tab.
+// Lint warnings, when enabled, are warning nodes after the content.
+// Diagnostics have the fields of errwrap.Diagnostic and list the errors,
+// then the warnings. A change to the schema that breaks readers increments
+// the version.
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/logger"
+	"github.com/umono-cms/compono/renderer/html"
+)
+
+// SchemaVersion is the version of the document schema.
+const SchemaVersion = 1
+
+// Renderer builds the document through the HTML renderer, whose overrides
+// write each node as a JSON value followed by a comma. Arrays are the
+// concatenated values of their children without the last comma.
+type Renderer struct {
+	html               *html.Renderer
+	preserveComponents bool
+}
+
+func NewRenderer(log logger.Logger) *Renderer {
+	r := &Renderer{
+		html: html.NewRenderer(log),
+	}
+
+	r.html.SetTextEscaper(textNode)
+	r.html.SetErrorRenderer(html.ErrorRendererFunc(errorNode))
+
+	for _, level := range []string{"h1", "h2", "h3", "h4", "h5", "h6"} {
+		r.html.Override(level+"-content", heading(level))
+	}
+	r.html.Override("p-content", parent("paragraph"))
+	r.html.Override("em-content", parent("emphasis"))
+	r.html.Override("strong-content", parent("strong"))
+	r.html.Override("soft-break", html.NodeRendererFunc(softBreak))
+	r.html.Override("inline-code", html.NodeRendererFunc(inlineCode))
+	r.html.Override("code-block", html.NodeRendererFunc(codeBlock))
+	r.html.Override("link", html.NodeRendererFunc(link))
+	r.html.OverrideBuiltin("LINK", html.NodeRendererFunc(builtinLink))
+	r.html.Override("block-comp-call", html.NodeRendererFunc(r.compCall))
+	r.html.Override("inline-comp-call", html.NodeRendererFunc(r.compCall))
+
+	return r
+}
+
+// SetPreserveComponents keeps component calls as component nodes around
+// their expansion.
+func (r *Renderer) SetPreserveComponents(preserve bool) {
+	r.preserveComponents = preserve
+}
+
+func (r *Renderer) Render(writer io.Writer, root ast.Node) error {
+	var sb strings.Builder
+	if err := r.html.Render(&sb, root); err != nil {
+		return err
+	}
+
+	collected := append([]*errwrap.Diagnostic{}, errwrap.Collect(root)...)
+	collected = append(collected, errwrap.CollectWarnings(root)...)
+	diagnostics, err := stdjson.Marshal(collected)
+	if err != nil {
+		return err
+	}
+
+	document := `{"version":` + strconv.Itoa(SchemaVersion) + `,"children":` + array(sb.String()) + `,"diagnostics":` + string(diagnostics) + `}`
+
+	var out bytes.Buffer
+	if err := stdjson.Indent(&out, []byte(document), "", "  "); err != nil {
+		return err
+	}
+
+	_, err = out.WriteTo(writer)
+	return err
+}
+
+func (r *Renderer) compCall(ctx *html.Context, node ast.Node) string {
+	expanded := ctx.RenderDefault()
+	if !r.preserveComponents {
+		return expanded
+	}
+
+	name := ""
+	if compCallName := ast.FindNodeByRuleName(node.Children(), "comp-call-name"); compCallName != nil {
+		name = strings.TrimSpace(string(compCallName.Raw()))
+	}
+
+	args := []string{}
+	for _, compCallArg := range ast.GetCompCallArgsFromCompCall(node) {
+		args = append(args, quote(ast.GetArgNameFromCompCallArg(compCallArg))+":"+argValue(compCallArg))
+	}
+
+	return `{"type":"component","name":` + quote(name) + `,"args":{` + strings.Join(args, ",") + `},"children":` + array(expanded) + `},`
+}
+
+func argValue(compCallArg ast.Node) string {
+	value := ast.GetArgValueFromCompCallArg(compCallArg)
+
+	switch ast.GetTypeFromCompCallArg(compCallArg) {
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return value
+		}
+	case "bool":
+		if value == "true" || value == "false" {
+			return value
+		}
+	}
+	return quote(value)
+}
+
+func textNode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return `{"type":"text","value":` + quote(s) + `},`
+}
+
+func errorNode(d *errwrap.Diagnostic) string {
+	diagnostic, err := stdjson.Marshal(d)
+	if err != nil {
+		return ""
+	}
+	nodeType := "error"
+	if d.Severity == errwrap.SeverityWarning {
+		nodeType = "warning"
+	}
+	return `{"type":"` + nodeType + `","diagnostic":` + string(diagnostic) + `},`
+}
+
+func heading(level string) html.NodeRenderer {
+	return html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		return `{"type":"heading","level":` + level[1:] + `,"children":` + array(ctx.RenderChildren(node.Children())) + `},`
+	})
+}
+
+func parent(nodeType string) html.NodeRenderer {
+	return html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		return `{"type":"` + nodeType + `","children":` + array(ctx.RenderChildren(node.Children())) + `},`
+	})
+}
+
+func softBreak(_ *html.Context, _ ast.Node) string {
+	return `{"type":"break"},`
+}
+
+func inlineCode(_ *html.Context, node ast.Node) string {
+	content := ast.FindNodeByRuleName(node.Children(), "inline-code-content")
+	if content == nil {
+		return ""
+	}
+	return `{"type":"code","value":` + quote(string(content.Raw())) + `},`
+}
+
+func codeBlock(_ *html.Context, node ast.Node) string {
+	lang := ""
+	if cbl := ast.FindNodeByRuleName(node.Children(), "code-block-lang"); cbl != nil {
+		lang = strings.TrimSpace(string(cbl.Raw()))
+	}
+
+	code := ""
+	if content := ast.FindNodeByRuleName(node.Children(), "code-block-content"); content != nil {
+		code = strings.Trim(string(content.Raw()), "\n")
+	}
+
+	return `{"type":"codeBlock","lang":` + quote(lang) + `,"value":` + quote(code) + `},`
+}
+
+func link(ctx *html.Context, node ast.Node) string {
+	children := ""
+	if linkText := ast.FindNodeByRuleName(node.Children(), "link-text"); linkText != nil {
+		children = ctx.RenderChildren(linkText.Children())
+	}
+
+	href := ""
+	if linkURL := ast.FindNodeByRuleName(node.Children(), "link-url"); linkURL != nil {
+		href = strings.TrimSpace(string(linkURL.Raw()))
+	}
+
+	rel, target := html.LinkRelTarget(node)
+	return linkNode(href, false, rel, target, children)
+}
+
+func builtinLink(_ *html.Context, node ast.Node) string {
+	text, _ := html.ArgValue(node, "text")
+	href, ok := html.ArgValue(node, "url")
+	if !ok {
+		href = "url"
+	}
+	newTab, _ := html.ArgValue(node, "new-tab")
+	rel, target := html.LinkRelTarget(node)
+
+	return linkNode(href, newTab == "true", rel, target, textNode(text))
+}
+
+// linkNode writes a link node, with rel and target only when they are set.
+func linkNode(href string, newTab bool, rel string, target string, children string) string {
+	attrs := ""
+	if rel != "" {
+		attrs += `,"rel":` + quote(rel)
+	}
+	if target != "" {
+		attrs += `,"target":` + quote(target)
+	}
+	return `{"type":"link","href":` + quote(href) + `,"newTab":` + strconv.FormatBool(newTab) + attrs + `,"children":` + array(children) + `},`
+}
+
+// array makes a JSON array of the comma terminated values.
+func array(values string) string {
+	return "[" + strings.TrimSuffix(values, ",") + "]"
+}
+
+func quote(s string) string {
+	quoted, _ := stdjson.Marshal(s)
+	return string(quoted)
+}
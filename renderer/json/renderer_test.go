@@ -0,0 +1,128 @@
+package json_test
+
+import (
+	stdjson "encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/umono-cms/compono"
+	"github.com/umono-cms/compono/links"
+	"github.com/umono-cms/compono/renderer/json"
+)
+
+type node struct {
+	Type     string         `json:"type"`
+	Value    string         `json:"value"`
+	Level    int            `json:"level"`
+	Href     string         `json:"href"`
+	NewTab   bool           `json:"newTab"`
+	Rel      string         `json:"rel"`
+	Target   string         `json:"target"`
+	Name     string         `json:"name"`
+	Args     map[string]any `json:"args"`
+	Children []node         `json:"children"`
+}
+
+type document struct {
+	Version     int    `json:"version"`
+	Children    []node `json:"children"`
+	Diagnostics []struct {
+		Severity string `json:"severity"`
+		Code     string `json:"code"`
+	} `json:"diagnostics"`
+}
+
+func render(t *testing.T, source string, preserve bool, opts ...compono.ConvertOption) document {
+	c := compono.New()
+	r := json.NewRenderer(c.Logger())
+	r.SetPreserveComponents(preserve)
+	c.SetRenderer(r)
+
+	var sb strings.Builder
+	require.Nil(t, c.Convert([]byte(source), &sb, opts...))
+
+	var doc document
+	require.Nil(t, stdjson.Unmarshal([]byte(sb.String()), &doc))
+	return doc
+}
+
+func TestRender(t *testing.T) {
+	doc := render(t, "# Hello *there*\n\n{{ LINK text=\"Home\" url=\"/\" new-tab=true }} and {{ MISSING }}", false)
+
+	assert.Equal(t, json.SchemaVersion, doc.Version)
+	require.Len(t, doc.Children, 2)
+
+	heading := doc.Children[0]
+	assert.Equal(t, "heading", heading.Type)
+	assert.Equal(t, 1, heading.Level)
+	require.Len(t, heading.Children, 2)
+	assert.Equal(t, "emphasis", heading.Children[1].Type)
+
+	paragraph := doc.Children[1]
+	assert.Equal(t, "paragraph", paragraph.Type)
+	require.Len(t, paragraph.Children, 3)
+	assert.Equal(t, "link", paragraph.Children[0].Type)
+	assert.Equal(t, "/", paragraph.Children[0].Href)
+	assert.True(t, paragraph.Children[0].NewTab)
+	assert.Equal(t, "error", paragraph.Children[2].Type)
+
+	require.Len(t, doc.Diagnostics, 1)
+	assert.Equal(t, "unknown-comp", doc.Diagnostics[0].Code)
+}
+
+func TestWarnings(t *testing.T) {
+	doc := render(t, "text\n\n~ UNUSED\nhi", false, compono.WithWarnings(true))
+
+	require.Len(t, doc.Children, 2)
+	assert.Equal(t, "paragraph", doc.Children[0].Type)
+	assert.Equal(t, "warning", doc.Children[1].Type)
+	require.Len(t, doc.Diagnostics, 1)
+	assert.Equal(t, "unused-comp", doc.Diagnostics[0].Code)
+	assert.Equal(t, "warning", doc.Diagnostics[0].Severity)
+
+	doc = render(t, "text\n\n~ UNUSED\nhi", false)
+	assert.Len(t, doc.Children, 1)
+	assert.Empty(t, doc.Diagnostics)
+}
+
+func TestLinkResolution(t *testing.T) {
+	resolver := links.ResolverFunc(func(url string) links.Resolution {
+		if url == "/" {
+			return links.Resolution{}
+		}
+		return links.Resolution{Rel: "nofollow", Target: "_blank"}
+	})
+	doc := render(t, "[Ext](https://example.com) {{ LINK text=\"Ext\" url=\"https://example.com\" }} [Home](/)", false, compono.WithLinkResolver(resolver))
+
+	require.Len(t, doc.Children, 1)
+	paragraph := doc.Children[0]
+	require.Len(t, paragraph.Children, 5)
+	for _, link := range []node{paragraph.Children[0], paragraph.Children[2]} {
+		assert.Equal(t, "link", link.Type)
+		assert.Equal(t, "nofollow", link.Rel)
+		assert.Equal(t, "_blank", link.Target)
+	}
+	assert.Empty(t, paragraph.Children[4].Rel)
+	assert.Empty(t, paragraph.Children[4].Target)
+}
+
+func TestPreserveComponents(t *testing.T) {
+	source := "{{ CARD title=\"Hi\" count=2 open=true }}\n\n~ CARD title = \"\" count = 0 open = false\n\n## {{ title }}"
+
+	expanded := render(t, source, false)
+	require.Len(t, expanded.Children, 1)
+	assert.Equal(t, "heading", expanded.Children[0].Type)
+
+	preserved := render(t, source, true)
+	require.Len(t, preserved.Children, 1)
+
+	card := preserved.Children[0]
+	assert.Equal(t, "component", card.Type)
+	assert.Equal(t, "CARD", card.Name)
+	assert.Equal(t, map[string]any{"title": "Hi", "count": float64(2), "open": true}, card.Args)
+	require.Len(t, card.Children, 1)
+	assert.Equal(t, "heading", card.Children[0].Type)
+	assert.Equal(t, 2, card.Children[0].Level)
+}
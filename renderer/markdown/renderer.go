@@ -0,0 +1,200 @@
+// Package markdown exports compono content as CommonMark, with every
+// component call and parameter reference expanded, for tools that consume
+// Markdown such as static site generators.
+package markdown
+
+import (
+	stdhtml "html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/logger"
+	"github.com/umono-cms/compono/renderer/html"
+)
+
+var (
+	blankLinesRe  = regexp.MustCompile(`\n{3,}`)
+	entityRe      = regexp.MustCompile(`&(#?[A-Za-z0-9]+;)`)
+	blockMarkerRe = regexp.MustCompile(`^([ \t]*)([#>+=-])`)
+	listNumberRe  = regexp.MustCompile(`^([ \t]*[0-9]+)([.)])`)
+	backticksRe   = regexp.MustCompile("`+")
+)
+
+// Renderer expands components like the HTML renderer, through which it
+// renders, and writes CommonMark in place of HTML. Text is escaped so that
+// it stays literal, code blocks keep their language and errors become HTML
+// comments. A link that opens in a new tab, or that the link resolver gave
+// a rel or target, is written as an HTML anchor, since CommonMark links have
+// neither.
+type Renderer struct {
+	html *html.Renderer
+}
+
+func NewRenderer(log logger.Logger) *Renderer {
+	r := &Renderer{
+		html: html.NewRenderer(log),
+	}
+
+	r.html.SetTextEscaper(Escape)
+	r.html.SetErrorRenderer(html.ErrorRendererFunc(renderError))
+
+	for _, level := range []string{"h1", "h2", "h3", "h4", "h5", "h6"} {
+		r.html.Override(level+"-content", heading(level))
+	}
+	r.html.Override("p-content", html.NodeRendererFunc(paragraph))
+	r.html.Override("soft-break", html.NodeRendererFunc(softBreak))
+	r.html.Override("em-content", delimited("*"))
+	r.html.Override("strong-content", delimited("**"))
+	r.html.Override("inline-code", html.NodeRendererFunc(inlineCode))
+	r.html.Override("code-block", html.NodeRendererFunc(codeBlock))
+	r.html.Override("link", html.NodeRendererFunc(link))
+	r.html.OverrideBuiltin("LINK", html.NodeRendererFunc(builtinLink))
+
+	return r
+}
+
+func (r *Renderer) Render(writer io.Writer, root ast.Node) error {
+	var sb strings.Builder
+	if err := r.html.Render(&sb, root); err != nil {
+		return err
+	}
+
+	markdown := blankLinesRe.ReplaceAllString(sb.String(), "\n\n")
+	_, err := io.WriteString(writer, strings.TrimRight(strings.TrimLeft(markdown, "\n"), " \t\n"))
+	return err
+}
+
+// Escape escapes the Markdown special characters of a piece of text, and
+// the block markers at its start, so that it renders literally. ! is
+// escaped too, since a link rendered after the text would otherwise make
+// an image.
+func Escape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>!", r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+
+	escaped := entityRe.ReplaceAllString(sb.String(), `\&$1`)
+	escaped = blockMarkerRe.ReplaceAllString(escaped, `$1\$2`)
+	return listNumberRe.ReplaceAllString(escaped, `$1\$2`)
+}
+
+func renderError(d *errwrap.Diagnostic) string {
+	comment := "<!-- " + strings.ReplaceAll(d.Title+": "+d.Message, "--", "- -") + " -->"
+	if d.Block {
+		return "\n\n" + comment + "\n\n"
+	}
+	return comment
+}
+
+func heading(level string) html.NodeRenderer {
+	return html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		text := strings.TrimSpace(ctx.RenderChildren(node.Children()))
+		return "\n\n" + strings.Repeat("#", int(level[1]-'0')) + " " + text + "\n\n"
+	})
+}
+
+func paragraph(ctx *html.Context, node ast.Node) string {
+	return "\n\n" + strings.TrimSpace(ctx.RenderChildren(node.Children())) + "\n\n"
+}
+
+func softBreak(_ *html.Context, _ ast.Node) string {
+	return "\n"
+}
+
+func delimited(delimiter string) html.NodeRenderer {
+	return html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		return delimiter + ctx.RenderChildren(node.Children()) + delimiter
+	})
+}
+
+func inlineCode(_ *html.Context, node ast.Node) string {
+	content := ast.FindNodeByRuleName(node.Children(), "inline-code-content")
+	if content == nil {
+		return ""
+	}
+
+	code := string(content.Raw())
+	fence := fenceFor(code, 1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+func codeBlock(_ *html.Context, node ast.Node) string {
+	content := ast.FindNodeByRuleName(node.Children(), "code-block-content")
+	if content == nil {
+		return ""
+	}
+
+	lang := ""
+	if cbl := ast.FindNodeByRuleName(node.Children(), "code-block-lang"); cbl != nil {
+		lang = strings.TrimSpace(string(cbl.Raw()))
+	}
+
+	code := strings.Trim(string(content.Raw()), "\n")
+	if code != "" {
+		code += "\n"
+	}
+	fence := fenceFor(code, 3)
+	return "\n\n" + fence + lang + "\n" + code + fence + "\n\n"
+}
+
+// fenceFor returns a backtick fence of at least min backticks that is
+// longer than every backtick run in code.
+func fenceFor(code string, min int) string {
+	length := min
+	for _, run := range backticksRe.FindAllString(code, -1) {
+		if len(run) >= length {
+			length = len(run) + 1
+		}
+	}
+	return strings.Repeat("`", length)
+}
+
+func link(ctx *html.Context, node ast.Node) string {
+	text := ""
+	if linkText := ast.FindNodeByRuleName(node.Children(), "link-text"); linkText != nil {
+		text = ctx.RenderChildren(linkText.Children())
+	}
+
+	url := ""
+	if linkURL := ast.FindNodeByRuleName(node.Children(), "link-url"); linkURL != nil {
+		url = strings.TrimSpace(string(linkURL.Raw()))
+	}
+
+	if attrs := html.LinkAttrs(node, false); attrs != "" {
+		return `<a href="` + stdhtml.EscapeString(url) + `"` + attrs + ">" + strings.TrimSpace(text) + "</a>"
+	}
+	return "[" + strings.TrimSpace(text) + "](" + destination(url) + ")"
+}
+
+func builtinLink(_ *html.Context, node ast.Node) string {
+	text, _ := html.ArgValue(node, "text")
+	url, ok := html.ArgValue(node, "url")
+	if !ok {
+		url = "url"
+	}
+
+	newTab, _ := html.ArgValue(node, "new-tab")
+	if attrs := html.LinkAttrs(node, newTab == "true"); attrs != "" {
+		return `<a href="` + stdhtml.EscapeString(url) + `"` + attrs + ">" + stdhtml.EscapeString(text) + "</a>"
+	}
+	return "[" + Escape(text) + "](" + destination(url) + ")"
+}
+
+// destination writes url as a link destination, in angle brackets when it
+// has spaces or parentheses.
+func destination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", `\<`, ">", `\>`).Replace(url) + ">"
+	}
+	return url
+}
@@ -0,0 +1,56 @@
+package markdown_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/umono-cms/compono"
+	"github.com/umono-cms/compono/links"
+	"github.com/umono-cms/compono/renderer/markdown"
+)
+
+func render(t *testing.T, source string, opts ...compono.ConvertOption) string {
+	c := compono.New()
+	c.SetRenderer(markdown.NewRenderer(c.Logger()))
+
+	var sb strings.Builder
+	require.Nil(t, c.Convert([]byte(source), &sb, opts...))
+	return sb.String()
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a \*b\* \_c\_ \[d\] \<e\> \`+"`f\\`"+` g\\h`, markdown.Escape("a *b* _c_ [d] <e> `f` g\\h"))
+	assert.Equal(t, `\# not a heading`, markdown.Escape("# not a heading"))
+	assert.Equal(t, `1\. not a list`, markdown.Escape("1. not a list"))
+	assert.Equal(t, `\- not a list`, markdown.Escape("- not a list"))
+	assert.Equal(t, `Fish & Chips \&amp;`, markdown.Escape("Fish & Chips &amp;"))
+	assert.Equal(t, `Wow\!`, markdown.Escape("Wow!"))
+}
+
+func TestRender(t *testing.T) {
+	assert.Equal(t, "## Price: 5 \\* 2\n\n[the *docs*](<https://example.com/a b>)",
+		render(t, "## Price: 5 * 2\n\n[the *docs*](https://example.com/a b)"))
+
+	assert.Equal(t, "```md\n# x\n```", render(t, "```md\n# x\n```"))
+
+	assert.Equal(t, "Hello <!-- Unknown component: The component MISSING is not defined or not registered. -->",
+		render(t, "Hello {{ MISSING }}"))
+
+	assert.Equal(t, "[Home \\*](/) and [Home](/)", render(t, `{{ LINK text="Home *" url="/" }} and [Home](/)`))
+
+	assert.Equal(t, "Wow\\![a](/x)", render(t, "Wow![a](/x)"))
+}
+
+func TestLinkResolution(t *testing.T) {
+	resolver := links.ResolverFunc(func(url string) links.Resolution {
+		if url == "/" {
+			return links.Resolution{}
+		}
+		return links.Resolution{Rel: "nofollow"}
+	})
+
+	assert.Equal(t, `<a href="https://example.com" rel="nofollow">the *docs*</a> <a href="https://example.com" rel="nofollow">Ext</a> [Home](/)`,
+		render(t, `[the *docs*](https://example.com) {{ LINK text="Ext" url="https://example.com" }} [Home](/)`, compono.WithLinkResolver(resolver)))
+}
@@ -0,0 +1,158 @@
+// Package text renders compono content as readable plain text, for email
+// text parts, search indexing and social media descriptions.
+package text
+
+import (
+	stdhtml "html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/logger"
+	"github.com/umono-cms/compono/renderer/html"
+)
+
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// Renderer expands components like the HTML renderer, through which it
+// renders, and writes text in place of markup. Headings of level 1 and 2 are
+// underlined, lower ones prefixed with #, links become "text (url)" and code
+// blocks are indented. Errors become bracketed notes unless omitted.
+//
+// Everything the overrides return stays HTML escaped, like the output of
+// the HTML renderer, and is unescaped once the whole text is rendered.
+type Renderer struct {
+	html       *html.Renderer
+	omitErrors bool
+}
+
+func NewRenderer(log logger.Logger) *Renderer {
+	r := &Renderer{
+		html: html.NewRenderer(log),
+	}
+
+	r.html.SetErrorRenderer(html.ErrorRendererFunc(r.renderError))
+
+	for _, level := range []string{"h1", "h2", "h3", "h4", "h5", "h6"} {
+		r.html.Override(level+"-content", heading(level))
+	}
+	r.html.Override("p-content", html.NodeRendererFunc(paragraph))
+	r.html.Override("soft-break", html.NodeRendererFunc(softBreak))
+	for _, ruleName := range []string{"em-content", "strong-content", "inline-code"} {
+		r.html.Override(ruleName, html.NodeRendererFunc(children))
+	}
+	r.html.Override("code-block", html.NodeRendererFunc(codeBlock))
+	r.html.Override("link", html.NodeRendererFunc(link))
+	r.html.OverrideBuiltin("LINK", html.NodeRendererFunc(builtinLink))
+
+	return r
+}
+
+// SetOmitErrors leaves errors and warnings out of the text instead of
+// rendering them as bracketed notes.
+func (r *Renderer) SetOmitErrors(omit bool) {
+	r.omitErrors = omit
+}
+
+func (r *Renderer) Render(writer io.Writer, root ast.Node) error {
+	var sb strings.Builder
+	if err := r.html.Render(&sb, root); err != nil {
+		return err
+	}
+
+	text := blankLinesRe.ReplaceAllString(stdhtml.UnescapeString(sb.String()), "\n\n")
+	// leading spaces are kept for a code block at the start
+	_, err := io.WriteString(writer, strings.TrimRight(strings.TrimLeft(text, "\n"), " \t\n"))
+	return err
+}
+
+func (r *Renderer) renderError(d *errwrap.Diagnostic) string {
+	if r.omitErrors {
+		return ""
+	}
+
+	note := "[" + stdhtml.EscapeString(d.Title+": "+d.Message) + "]"
+	if d.Block {
+		return "\n\n" + note + "\n\n"
+	}
+	return note
+}
+
+func heading(level string) html.NodeRenderer {
+	return html.NodeRendererFunc(func(ctx *html.Context, node ast.Node) string {
+		text := strings.TrimSpace(ctx.RenderChildren(node.Children()))
+		width := utf8.RuneCountInString(stdhtml.UnescapeString(text))
+
+		switch level {
+		case "h1":
+			return "\n\n" + text + "\n" + strings.Repeat("=", width) + "\n\n"
+		case "h2":
+			return "\n\n" + text + "\n" + strings.Repeat("-", width) + "\n\n"
+		default:
+			return "\n\n" + strings.Repeat("#", int(level[1]-'0')) + " " + text + "\n\n"
+		}
+	})
+}
+
+func paragraph(ctx *html.Context, node ast.Node) string {
+	return "\n\n" + strings.TrimSpace(ctx.RenderChildren(node.Children())) + "\n\n"
+}
+
+func softBreak(_ *html.Context, _ ast.Node) string {
+	return "\n"
+}
+
+func children(ctx *html.Context, node ast.Node) string {
+	return ctx.RenderChildren(node.Children())
+}
+
+func codeBlock(ctx *html.Context, node ast.Node) string {
+	content := ast.FindNodeByRuleName(node.Children(), "code-block-content")
+	if content == nil {
+		return ""
+	}
+
+	code := strings.Trim(ctx.RenderChildren([]ast.Node{content}), "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+	return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+}
+
+func link(ctx *html.Context, node ast.Node) string {
+	text := ""
+	if linkText := ast.FindNodeByRuleName(node.Children(), "link-text"); linkText != nil {
+		text = ctx.RenderChildren(linkText.Children())
+	}
+
+	url := ""
+	if linkURL := ast.FindNodeByRuleName(node.Children(), "link-url"); linkURL != nil {
+		url = stdhtml.EscapeString(strings.TrimSpace(string(linkURL.Raw())))
+	}
+
+	return linkText(text, url)
+}
+
+func builtinLink(_ *html.Context, node ast.Node) string {
+	text, _ := html.ArgValue(node, "text")
+	url, ok := html.ArgValue(node, "url")
+	if !ok {
+		url = "url"
+	}
+	return linkText(stdhtml.EscapeString(text), stdhtml.EscapeString(url))
+}
+
+func linkText(text string, url string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || text == url {
+		return url
+	}
+	return text + " (" + url + ")"
+}
@@ -0,0 +1,38 @@
+package text_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/umono-cms/compono/ast"
+	"github.com/umono-cms/compono/errwrap"
+	"github.com/umono-cms/compono/logger"
+	"github.com/umono-cms/compono/parser"
+	"github.com/umono-cms/compono/renderer/text"
+)
+
+func render(t *testing.T, r *text.Renderer, source string) string {
+	root := parser.DefaultParser(logger.NewLogger()).Parse([]byte(source), ast.DefaultRootNode())
+	errwrap.DefaultErrorWrapper().Wrap(root)
+
+	var sb strings.Builder
+	require.Nil(t, r.Render(&sb, root))
+	return sb.String()
+}
+
+func TestRender(t *testing.T) {
+	r := text.NewRenderer(logger.NewLogger())
+
+	assert.Equal(t, "Fish & <Chips>\n==============\n\n#### Menu\n\nSee menu (https://example.com/?a=1&b=2)",
+		render(t, r, "# Fish & <Chips>\n\n#### Menu\n\nSee [menu](https://example.com/?a=1&b=2)"))
+
+	assert.Equal(t, "    if a < b && c {\n    }", render(t, r, "```go\nif a < b && c {\n}\n```"))
+
+	assert.Equal(t, "Hello [Unknown component: The component MISSING is not defined or not registered.]",
+		render(t, r, "Hello {{ MISSING }}"))
+
+	r.SetOmitErrors(true)
+	assert.Equal(t, "Hello", render(t, r, "Hello {{ MISSING }}"))
+}
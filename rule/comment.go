@@ -0,0 +1,79 @@
+package rule
+
+import (
+	"github.com/umono-cms/compono/selector"
+)
+
+// Comment, a line starting with %%. It is never rendered. A comment between
+// two lines of a paragraph is left to the paragraph, whose content takes it
+// up, so that it does not end the paragraph.
+type comment struct {
+	// inParagraph is set for the comments of a paragraph's content.
+	inParagraph bool
+}
+
+func newComment() Rule {
+	return &comment{}
+}
+
+func newParagraphComment() Rule {
+	return &comment{inParagraph: true}
+}
+
+func (_ *comment) Name() string {
+	return "comment"
+}
+
+func (c *comment) Selectors() []selector.Selector {
+	se, _ := selector.NewStartEnd(`(?m)^[ \t]*%%`, `\n|\z`)
+	lines := selector.NewFilter(se, func(source []byte, index [][2]int) [][2]int {
+		if len(index) == 0 {
+			return [][2]int{}
+		}
+
+		filtered := [][2]int{}
+		for _, ind := range index {
+			if ind[0] == 0 || source[ind[0]-1] == '\n' {
+				filtered = append(filtered, ind)
+			}
+		}
+		return filtered
+	})
+
+	if c.inParagraph {
+		return []selector.Selector{lines}
+	}
+	return []selector.Selector{
+		selector.NewOutsideParagraph(lines),
+	}
+}
+
+func (_ *comment) Rules() []Rule {
+	return []Rule{}
+}
+
+// Component documentation, the comments at the top of a global component
+// source, blank lines between them included, or directly above the head of
+// a local component.
+type compDoc struct{}
+
+func newCompDoc() Rule {
+	return &compDoc{}
+}
+
+func (_ *compDoc) Name() string {
+	return "comp-doc"
+}
+
+func (_ *compDoc) Selectors() []selector.Selector {
+	p, _ := selector.NewPattern(`\A(?:[ \t]*\n)*[ \t]*%%[^\n]*(?:\n(?:[ \t]*\n)*[ \t]*%%[^\n]*)*(?:\n(?:[ \t]*\n)*|\z)`)
+	return []selector.Selector{
+		p,
+	}
+}
+
+func (_ *compDoc) Rules() []Rule {
+	return []Rule{
+		newComment(),
+	}
+}
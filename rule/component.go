@@ -20,7 +20,7 @@ func (_ *localCompDefWrapper) Name() string {
 
 func (_ *localCompDefWrapper) Selectors() []selector.Selector {
 	return []selector.Selector{
-		selector.NewSinceFirstMatchInner(`\n*~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*`),
+		selector.NewSinceFirstMatchInner(`\n*(?:[ \t]*%%[^\n]*\n)*~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*`),
 	}
 }
 
@@ -42,7 +42,7 @@ func (_ *localCompDef) Name() string {
 }
 
 func (_ *localCompDef) Selectors() []selector.Selector {
-	seli, _ := selector.NewStartEndLeftInner(`(?:\n|\A)~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*`, `\n~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*|\z`)
+	seli, _ := selector.NewStartEndLeftInner(`(?:\n|\A)(?:[ \t]*%%[^\n]*\n)*~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*`, `\n(?:[ \t]*%%[^\n]*\n)*~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*|\z`)
 	return []selector.Selector{
 		seli,
 	}
@@ -50,6 +50,7 @@ func (_ *localCompDef) Selectors() []selector.Selector {
 
 func (_ *localCompDef) Rules() []Rule {
 	return []Rule{
+		newCompDoc(),
 		newLocalCompDefHead(),
 		newLocalCompDefContent(),
 	}
@@ -114,7 +115,7 @@ func (_ *compParams) Name() string {
 
 func (_ *compParams) Selectors() []selector.Selector {
 	se, _ := selector.NewStartEnd(`.`, `.`)
-	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)(?:[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*))?`)
+	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)(?:[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*))?`)
 	return []selector.Selector{
 		selector.NewBounds(se, p),
 	}
@@ -138,7 +139,7 @@ func (_ *compParam) Name() string {
 }
 
 func (_ *compParam) Selectors() []selector.Selector {
-	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)(?:[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*))?`)
+	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)(?:[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*))?`)
 	return []selector.Selector{
 		p,
 	}
@@ -186,7 +187,7 @@ func (_ *compParamType) Name() string {
 }
 
 func (_ *compParamType) Selectors() []selector.Selector {
-	p, _ := selector.NewPattern(`[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*)`)
+	p, _ := selector.NewPattern(`[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*)`)
 	return []selector.Selector{
 		p,
 	}
@@ -284,7 +285,7 @@ func (_ *compCompParam) Name() string {
 }
 
 func (_ *compCompParam) Selectors() []selector.Selector {
-	p, _ := selector.NewPattern(`[A-Z0-9]+(?:_[A-Z0-9]+)*`)
+	p, _ := selector.NewPattern(`[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*`)
 	return []selector.Selector{
 		p,
 	}
@@ -345,6 +346,7 @@ func (_ *localCompDefContent) Rules() []Rule {
 		newH2(),
 		newH1(),
 		newBlockCompCall(),
+		newComment(),
 		newP(),
 	}
 }
@@ -413,7 +415,7 @@ func (_ *blockCompCall) Name() string {
 }
 
 func (_ *blockCompCall) Selectors() []selector.Selector {
-	se, _ := selector.NewStartEnd(`\{\{\s*[A-Z0-9]+(?:_[A-Z0-9]+)*`, `\s*\}\}`)
+	se, _ := selector.NewStartEnd(`\{\{\s*[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*`, `\s*\}\}`)
 	return []selector.Selector{
 		selector.NewFilter(se, func(source []byte, index [][2]int) [][2]int {
 			if len(index) == 0 {
@@ -486,7 +488,7 @@ func (_ *compCall) Name() string {
 }
 
 func (_ *compCall) Selectors() []selector.Selector {
-	seSelector, _ := selector.NewStartEnd(`\{\{\s*[A-Z0-9]+(?:_[A-Z0-9]+)*`, `\s*\}\}`)
+	seSelector, _ := selector.NewStartEnd(`\{\{\s*[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*`, `\s*\}\}`)
 	return []selector.Selector{
 		seSelector,
 	}
@@ -511,7 +513,7 @@ func (_ *compCallName) Name() string {
 }
 
 func (_ *compCallName) Selectors() []selector.Selector {
-	p, _ := selector.NewPattern(`\s*[A-Z0-9]+(?:_[A-Z0-9]+)*\s*`)
+	p, _ := selector.NewPattern(`\s*[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*\s*`)
 	return []selector.Selector{
 		selector.NewFilter(p, func(source []byte, index [][2]int) [][2]int {
 			if len(index) > 0 {
@@ -538,7 +540,7 @@ func (_ *compCallArgs) Name() string {
 }
 
 func (_ *compCallArgs) Selectors() []selector.Selector {
-	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[a-z][a-z0-9-]*|[A-Z0-9]+(?:_[A-Z0-9]+)*)`)
+	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[a-z][a-z0-9-]*|[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*)`)
 	return []selector.Selector{
 		selector.NewFilter(p, func(source []byte, index [][2]int) [][2]int {
 			if len(index) == 0 {
@@ -580,7 +582,7 @@ func (_ *compCallArg) Name() string {
 }
 
 func (_ *compCallArg) Selectors() []selector.Selector {
-	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[a-z][a-z0-9-]*|[A-Z0-9]+(?:_[A-Z0-9]+)*)`)
+	p, _ := selector.NewPattern(`([a-z][a-z0-9-]*)[\s\n\r]*=[\s\n\r]*(".*?"|\d+(?:\.\d+)?|true|false|[a-z][a-z0-9-]*|[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*)`)
 	return []selector.Selector{
 		p,
 	}
@@ -785,7 +787,7 @@ func (_ *compCallCompArg) Name() string {
 }
 
 func (_ *compCallCompArg) Selectors() []selector.Selector {
-	p, _ := selector.NewPattern(`[A-Z0-9]+(?:_[A-Z0-9]+)*`)
+	p, _ := selector.NewPattern(`[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*`)
 	return []selector.Selector{
 		p,
 	}
@@ -835,6 +837,7 @@ func (_ *globalCompDef) Selectors() []selector.Selector {
 
 func (_ *globalCompDef) Rules() []Rule {
 	return []Rule{
+		newCompDoc(),
 		NewGlobalCompDefHead(),
 		newGlobalCompDefContent(),
 		newLocalCompDefWrapper(),
@@ -872,7 +875,7 @@ func (_ *globalCompDefHead) Name() string {
 }
 
 func (_ *globalCompDefHead) Selectors() []selector.Selector {
-	p, _ := selector.NewStartEnd(`^([a-z][a-z0-9-]*)[ \t\r\n]*=[ \t\r\n]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*)`, `\n|\z`)
+	p, _ := selector.NewStartEnd(`^([a-z][a-z0-9-]*)[ \t\r\n]*=[ \t\r\n]*(".*?"|\d+(?:\.\d+)?|true|false|[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*)`, `\n|\z`)
 	return []selector.Selector{
 		p,
 	}
@@ -896,7 +899,7 @@ func (_ *globalCompDefContent) Name() string {
 }
 
 func (_ *globalCompDefContent) Selectors() []selector.Selector {
-	seli, _ := selector.NewStartEndLeftInner(`^`, `\n~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*|\z`)
+	seli, _ := selector.NewStartEndLeftInner(`^`, `\n(?:[ \t]*%%[^\n]*\n)*~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*|\z`)
 	return []selector.Selector{
 		seli,
 	}
@@ -905,6 +908,7 @@ func (_ *globalCompDefContent) Selectors() []selector.Selector {
 func (_ *globalCompDefContent) Rules() []Rule {
 	return []Rule{
 		newCodeBlock(),
+		newLayoutDecl(),
 		newH6(),
 		newH5(),
 		newH4(),
@@ -912,6 +916,7 @@ func (_ *globalCompDefContent) Rules() []Rule {
 		newH2(),
 		newH1(),
 		newBlockCompCall(),
+		newComment(),
 		newP(),
 	}
 }
@@ -0,0 +1,60 @@
+package rule
+
+import "github.com/umono-cms/compono/selector"
+
+// Layout declaration, "~~ LAYOUT NAME" on its own line
+type layoutDecl struct{}
+
+func newLayoutDecl() Rule {
+	return &layoutDecl{}
+}
+
+func (_ *layoutDecl) Name() string {
+	return "layout-decl"
+}
+
+func (_ *layoutDecl) Selectors() []selector.Selector {
+	se, _ := selector.NewStartEnd(`(?m)^~~[ \t]+LAYOUT[ \t]+[A-Z0-9]+(?:_[A-Z0-9]+)*(?:\.[A-Z0-9]+(?:_[A-Z0-9]+)*)*`, `[ \t]*(?:\n|\z)`)
+	return []selector.Selector{
+		selector.NewFilter(se, func(source []byte, index [][2]int) [][2]int {
+			if len(index) == 0 {
+				return [][2]int{}
+			}
+
+			filtered := [][2]int{}
+			for _, ind := range index {
+				if ind[0] == 0 || source[ind[0]-1] == '\n' {
+					filtered = append(filtered, ind)
+				}
+			}
+			return filtered
+		}),
+	}
+}
+
+func (_ *layoutDecl) Rules() []Rule {
+	return []Rule{
+		newLayoutName(),
+	}
+}
+
+// Layout name
+type layoutName struct{}
+
+func newLayoutName() Rule {
+	return &layoutName{}
+}
+
+func (_ *layoutName) Name() string {
+	return "layout-name"
+}
+
+func (_ *layoutName) Selectors() []selector.Selector {
+	return []selector.Selector{
+		selector.NewStartEndInner(`~~[ \t]+LAYOUT[ \t]+`, `[ \t]*(?:\n|\z)`),
+	}
+}
+
+func (_ *layoutName) Rules() []Rule {
+	return []Rule{}
+}
@@ -114,6 +114,7 @@ func (_ *pContent) Selectors() []selector.Selector {
 
 func (_ *pContent) Rules() []Rule {
 	return []Rule{
+		newParagraphComment(),
 		newLink(),
 		newInlineCode(),
 		newStrong(),
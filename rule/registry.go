@@ -0,0 +1,56 @@
+package rule
+
+import "sync"
+
+var (
+	registryOnce sync.Once
+	registryMu   sync.RWMutex
+	registry     map[string]Rule
+)
+
+// Register adds r to the rules Lookup resolves, replacing the rule of the
+// same name. The rules of the grammar are registered already.
+func Register(r Rule) {
+	registryOnce.Do(fillRegistry)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[r.Name()] = r
+}
+
+// Lookup returns the rule of the given name. Names that are not registered,
+// like those of the nodes built outside the parser, get a dynamic rule.
+func Lookup(name string) Rule {
+	registryOnce.Do(fillRegistry)
+
+	registryMu.RLock()
+	r, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return NewDynamic(name)
+	}
+	return r
+}
+
+// fillRegistry registers every rule reachable from the roots of pages and
+// global components.
+func fillRegistry() {
+	registry = map[string]Rule{}
+
+	var walk func(r Rule)
+	walk = func(r Rule) {
+		if _, ok := registry[r.Name()]; ok {
+			return
+		}
+		registry[r.Name()] = r
+		for _, child := range r.Rules() {
+			walk(child)
+		}
+	}
+
+	walk(NewRoot())
+	walk(NewGlobalCompDef())
+	walk(NewGlobalCompDefWrapper())
+	walk(NewGlobalCompName())
+}
@@ -34,7 +34,8 @@ func (_ *rootContent) Name() string {
 }
 
 func (_ *rootContent) Selectors() []selector.Selector {
-	seli, _ := selector.NewStartEndLeftInner(`^`, `\n~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*|\z`)
+	// the comments directly above a local component document it
+	seli, _ := selector.NewStartEndLeftInner(`^`, `\n(?:[ \t]*%%[^\n]*\n)*~\s+[A-Z0-9]+(?:_[A-Z0-9]+)*|\z`)
 	return []selector.Selector{
 		seli,
 	}
@@ -43,6 +44,7 @@ func (_ *rootContent) Selectors() []selector.Selector {
 func (_ *rootContent) Rules() []Rule {
 	return []Rule{
 		newCodeBlock(),
+		newLayoutDecl(),
 		newH6(),
 		newH5(),
 		newH4(),
@@ -50,6 +52,7 @@ func (_ *rootContent) Rules() []Rule {
 		newH2(),
 		newH1(),
 		newBlockCompCall(),
+		newComment(),
 		newP(),
 	}
 }
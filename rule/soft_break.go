@@ -15,7 +15,7 @@ func (_ *softBreak) Name() string {
 func (_ *softBreak) Selectors() []selector.Selector {
 	p, _ := selector.NewPattern(`\n`)
 	return []selector.Selector{
-		p,
+		selector.NewOutside(p),
 	}
 }
 
@@ -0,0 +1,39 @@
+package selector
+
+type outside struct {
+	selector Selector
+}
+
+// NewOutside keeps the ranges of selector that do not overlap the ranges
+// selected before, for selectors that select in the whole source, like
+// patterns.
+func NewOutside(selector Selector) Selector {
+	return &outside{
+		selector: selector,
+	}
+}
+
+func (o *outside) Name() string {
+	name := "unknown"
+	if n, ok := o.selector.(Named); ok {
+		name = n.Name()
+	}
+	return "outside(" + name + ")"
+}
+
+func (o *outside) Select(source []byte, without ...[2]int) [][2]int {
+	results := [][2]int{}
+	for _, index := range o.selector.Select(source, without...) {
+		overlaps := false
+		for _, w := range without {
+			if index[0] < w[1] && index[1] > w[0] {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			results = append(results, index)
+		}
+	}
+	return results
+}
@@ -0,0 +1,75 @@
+package selector
+
+import "bytes"
+
+type outsideParagraph struct {
+	selector Selector
+}
+
+// NewOutsideParagraph drops the ranges of selector, comment lines, whose
+// nearest lines that are not comments, above and below, are paragraph
+// lines: lines with text that no block selected before.
+func NewOutsideParagraph(selector Selector) Selector {
+	return &outsideParagraph{
+		selector: selector,
+	}
+}
+
+func (op *outsideParagraph) Name() string {
+	name := "unknown"
+	if n, ok := op.selector.(Named); ok {
+		name = n.Name()
+	}
+	return "outside_paragraph(" + name + ")"
+}
+
+func (op *outsideParagraph) Select(source []byte, without ...[2]int) [][2]int {
+	isParagraphLine := func(start, end int) bool {
+		if len(bytes.TrimSpace(source[start:end])) == 0 {
+			return false
+		}
+		for _, w := range without {
+			if start >= w[0] && start < w[1] {
+				return false
+			}
+		}
+		return true
+	}
+	isComment := func(start, end int) bool {
+		return bytes.HasPrefix(bytes.TrimLeft(source[start:end], " \t"), []byte("%%"))
+	}
+
+	filtered := [][2]int{}
+	for _, ind := range op.selector.Select(source, without...) {
+		above := false
+		for end := ind[0] - 1; end >= 0; {
+			start := bytes.LastIndexByte(source[:end], '\n') + 1
+			if !isComment(start, end) {
+				above = isParagraphLine(start, end)
+				break
+			}
+			end = start - 1
+		}
+
+		below := false
+		for start := ind[1]; start < len(source); {
+			if start > 0 && source[start-1] != '\n' {
+				break
+			}
+			end := len(source)
+			if i := bytes.IndexByte(source[start:], '\n'); i >= 0 {
+				end = start + i
+			}
+			if !isComment(start, end) {
+				below = isParagraphLine(start, end)
+				break
+			}
+			start = end + 1
+		}
+
+		if !above || !below {
+			filtered = append(filtered, ind)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,17 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutsideParagraph(t *testing.T) {
+	p, _ := NewPattern(`(?m)^%%[^\n]*\n?`)
+
+	assert.Equal(t, [][2]int{}, NewOutsideParagraph(p).Select([]byte("a\n%% b\n%% c\nd")))
+	assert.Equal(t, [][2]int{{3, 8}}, NewOutsideParagraph(p).Select([]byte("a\n\n%% b\nd")))
+	assert.Equal(t, [][2]int{{0, 5}}, NewOutsideParagraph(p).Select([]byte("%% b\nd")))
+	assert.Equal(t, [][2]int{}, NewOutsideParagraph(p).Select([]byte("# a\n%% b\nc")))
+	assert.Equal(t, [][2]int{{4, 9}}, NewOutsideParagraph(p).Select([]byte("# a\n%% b\nc"), [2]int{0, 3}))
+}
@@ -0,0 +1,15 @@
+package selector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutside(t *testing.T) {
+	p, _ := NewPattern(`\n`)
+	source := []byte("a\n%% b\nc\n")
+
+	assert.Equal(t, [][2]int{{1, 2}, {6, 7}, {8, 9}}, NewOutside(p).Select(source))
+	assert.Equal(t, [][2]int{{1, 2}, {8, 9}}, NewOutside(p).Select(source, [2]int{2, 7}))
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/umono-cms/compono/util"
+)
+
+const fileExt = ".comp"
+
+// FS reads global components from NAME.comp files at the root of a file
+// system. Namespaced components live in subdirectories, SHOP.CARD in
+// SHOP/CARD.comp. Leading and trailing whitespace of a file is ignored.
+type FS struct {
+	fsys fs.FS
+}
+
+func NewFS(fsys fs.FS) *FS {
+	return &FS{fsys: fsys}
+}
+
+func (f *FS) Lookup(name string) (Component, bool, error) {
+	if !util.IsComponentName(name) {
+		return Component{}, false, nil
+	}
+
+	source, err := fs.ReadFile(f.fsys, strings.ReplaceAll(name, ".", "/")+fileExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Component{}, false, nil
+	}
+	if err != nil {
+		return Component{}, false, err
+	}
+
+	source = bytes.TrimSpace(source)
+	sum := sha256.Sum256(source)
+
+	return Component{
+		Name:    name,
+		Source:  source,
+		Version: hex.EncodeToString(sum[:8]),
+	}, true, nil
+}
+
+func (f *FS) List() ([]string, error) {
+	names := []string{}
+	err := fs.WalkDir(f.fsys, ".", func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(p, fileExt) {
+			return nil
+		}
+		segments := strings.Split(strings.TrimSuffix(p, fileExt), "/")
+		for _, segment := range segments {
+			if !util.IsScreamingSnakeCase(segment) {
+				return nil
+			}
+		}
+		names = append(names, strings.Join(segments, "."))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
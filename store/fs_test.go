@@ -0,0 +1,49 @@
+package store
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"HEADER.comp":     {Data: []byte("# Header\n")},
+		"FOOTER.comp":     {Data: []byte("Footer")},
+		"notes.txt":       {Data: []byte("ignored")},
+		"lower_case.comp": {Data: []byte("ignored")},
+		"SHOP/CARD.comp":  {Data: []byte("Card")},
+		"shop/X.comp":     {Data: []byte("ignored")},
+	}
+	s := NewFS(fsys)
+
+	names, err := s.List()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"FOOTER", "HEADER", "SHOP.CARD"}, names)
+
+	comp, ok, err := s.Lookup("HEADER")
+	require.Nil(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "# Header", string(comp.Source))
+	assert.NotEmpty(t, comp.Version)
+
+	fsys["HEADER.comp"] = &fstest.MapFile{Data: []byte("# New header")}
+	changed, _, _ := s.Lookup("HEADER")
+	assert.NotEqual(t, comp.Version, changed.Version)
+
+	_, ok, err = s.Lookup("MISSING")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	card, ok, err := s.Lookup("SHOP.CARD")
+	require.Nil(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "SHOP.CARD", card.Name)
+	assert.Equal(t, "Card", string(card.Source))
+
+	_, ok, err = s.Lookup("../HEADER")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
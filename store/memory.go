@@ -0,0 +1,60 @@
+package store
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type Memory struct {
+	mu         sync.RWMutex
+	components map[string]Component
+	version    int
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		components: map[string]Component{},
+	}
+}
+
+func (m *Memory) Lookup(name string) (Component, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	comp, ok := m.components[name]
+	return comp, ok, nil
+}
+
+func (m *Memory) List() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.components))
+	for name := range m.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Set stores a copy of source as the component under a new version.
+func (m *Memory) Set(name string, source []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.version++
+	m.components[name] = Component{
+		Name:    name,
+		Source:  bytes.Clone(source),
+		Version: strconv.Itoa(m.version),
+	}
+}
+
+func (m *Memory) Delete(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.components, name)
+}
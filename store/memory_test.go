@@ -0,0 +1,35 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	s := NewMemory()
+
+	source := []byte("# Header")
+	s.Set("HEADER", source)
+	source[2] = 'X'
+
+	comp, ok, err := s.Lookup("HEADER")
+	require.Nil(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "# Header", string(comp.Source))
+
+	s.Set("HEADER", []byte("# New header"))
+	changed, _, _ := s.Lookup("HEADER")
+	assert.NotEqual(t, comp.Version, changed.Version)
+
+	s.Set("FOOTER", []byte("Footer"))
+	names, err := s.List()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"FOOTER", "HEADER"}, names)
+
+	s.Delete("HEADER")
+	_, ok, err = s.Lookup("HEADER")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
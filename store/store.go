@@ -0,0 +1,17 @@
+package store
+
+// Component is the source of a global component as kept by a store.
+// Version changes whenever Source does, so parsed components can be cached
+// until then.
+type Component struct {
+	Name    string
+	Source  []byte
+	Version string
+}
+
+// ComponentStore provides global components on demand, when a conversion
+// calls one that is not registered.
+type ComponentStore interface {
+	Lookup(name string) (Component, bool, error)
+	List() ([]string, error)
+}
@@ -0,0 +1,96 @@
+// Package urlpolicy decides which URLs links may point to, so that content
+// from semi-trusted authors cannot smuggle javascript: or data: URLs into
+// pages.
+package urlpolicy
+
+import (
+	"strings"
+)
+
+// Action is what happens to a link whose URL a policy rejects.
+type Action int
+
+const (
+	// Replace renders the link with the policy's replacement URL.
+	Replace Action = iota
+	// Report turns the link into an error, which strict mode and Lint
+	// report as a diagnostic.
+	Report
+)
+
+// Policy allows URLs with one of its schemes and, with AllowRelative,
+// URLs without a scheme.
+type Policy struct {
+	// Schemes are the allowed schemes, such as "https". They are matched
+	// case-insensitively.
+	Schemes       []string
+	AllowRelative bool
+	Action        Action
+	// Replacement is the URL rejected URLs are replaced with.
+	Replacement string
+}
+
+// Default allows http, https, mailto and tel URLs and relative URLs, and
+// replaces the others with "#".
+func Default() *Policy {
+	return &Policy{
+		Schemes:       []string{"http", "https", "mailto", "tel"},
+		AllowRelative: true,
+		Action:        Replace,
+		Replacement:   "#",
+	}
+}
+
+// Allows reports whether the policy allows rawURL. A nil policy allows
+// every URL.
+func (p *Policy) Allows(rawURL string) bool {
+	if p == nil {
+		return true
+	}
+
+	scheme := Scheme(rawURL)
+	if scheme == "" {
+		return p.AllowRelative
+	}
+	for _, allowed := range p.Schemes {
+		if strings.EqualFold(allowed, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scheme returns the lowercased scheme of rawURL as a browser reads it, or
+// "" for a relative URL. Browsers ignore tabs and newlines anywhere in a URL
+// and control characters and spaces around it, so "java\tscript:" is a
+// javascript: URL.
+func Scheme(rawURL string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, rawURL)
+	cleaned = strings.TrimFunc(cleaned, func(r rune) bool {
+		return r <= ' '
+	})
+
+	for i, r := range cleaned {
+		switch {
+		case r == ':' && i > 0:
+			return strings.ToLower(cleaned[:i])
+		case isLetter(r), i > 0 && (isDigit(r) || r == '+' || r == '-' || r == '.'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,50 @@
+package urlpolicy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/umono-cms/compono/urlpolicy"
+)
+
+func TestScheme(t *testing.T) {
+	tests := map[string]string{
+		"https://umono.io":         "https",
+		"MAILTO:a@b.c":             "mailto",
+		"javascript:alert(1)":      "javascript",
+		" \x01JavaScript:alert(1)": "javascript",
+		"java\tscr\nipt:alert(1)":  "javascript",
+		"svn+ssh://host":           "svn+ssh",
+		"/about":                   "",
+		"about-us":                 "",
+		"./a:b":                    "",
+		"#top":                     "",
+		"?q=a:b":                   "",
+		"1http://x":                "",
+		":x":                       "",
+		"":                         "",
+		"java script:alert(1)":     "",
+	}
+
+	for rawURL, want := range tests {
+		assert.Equal(t, want, urlpolicy.Scheme(rawURL), rawURL)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	policy := urlpolicy.Default()
+	assert.True(t, policy.Allows("https://umono.io"))
+	assert.True(t, policy.Allows("HTTP://umono.io"))
+	assert.True(t, policy.Allows("tel:+90"))
+	assert.True(t, policy.Allows("/docs"))
+	assert.False(t, policy.Allows("javascript:alert(1)"))
+	assert.False(t, policy.Allows("data:text/html,x"))
+
+	policy = &urlpolicy.Policy{Schemes: []string{"HTTPS"}}
+	assert.True(t, policy.Allows("https://umono.io"))
+	assert.False(t, policy.Allows("http://umono.io"))
+	assert.False(t, policy.Allows("/docs"))
+
+	var none *urlpolicy.Policy
+	assert.True(t, none.Allows("javascript:alert(1)"))
+}
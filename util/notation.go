@@ -1,8 +1,22 @@
 package util
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
 func IsScreamingSnakeCase(val string) bool {
 	re := regexp.MustCompile(`^[A-Z0-9]+(?:_[A-Z0-9]+)*$`)
 	return re.MatchString(val)
 }
+
+// IsComponentName reports whether val is a component name, optionally
+// qualified with dotted namespaces, such as SHOP.PRODUCT_CARD.
+func IsComponentName(val string) bool {
+	for _, segment := range strings.Split(val, ".") {
+		if !IsScreamingSnakeCase(segment) {
+			return false
+		}
+	}
+	return true
+}